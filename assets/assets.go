@@ -0,0 +1,10 @@
+// Package assets holds the files that ship inside the kata binary.
+package assets
+
+import "embed"
+
+// Languages holds the language packs under languages/, registered as
+// built-ins next to the languages defined in code.
+//
+//go:embed languages/*.yaml
+var Languages embed.FS
@@ -1,8 +1,11 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -10,7 +13,10 @@ import (
 
 	"kata/pkg/config"
 	"kata/pkg/engine"
+	"kata/pkg/extract"
 	"kata/pkg/generator"
+	"kata/pkg/keyboard"
+	"kata/pkg/progression"
 	"kata/pkg/stats"
 	"kata/pkg/themes"
 )
@@ -20,15 +26,84 @@ func New() tea.Model {
 	return initialModel()
 }
 
-// NewPractice creates a new TUI application model starting directly in practice mode
-func NewPractice(targetText string) tea.Model {
+// NewPractice creates a new TUI application model starting directly in practice mode.
+// source is the file the text came from, if any.
+func NewPractice(targetText string, lessonType generator.LessonType, source string) tea.Model {
 	m := initialModel()
 	m.targetText = strings.TrimSpace(targetText)
+	m.lessonType = lessonType
+	m.lessonSource = source
 	m.startPractice()
 	return m
 }
 
+// NewFile returns a model that practises a file chapter by chapter,
+// offering to resume where the last session on it stopped.
+func NewFile(path string) (tea.Model, error) {
+	m := initialModel()
+	if err := m.startFile(path); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewTraining returns a model that starts a lesson at the user's current
+// curriculum level.
+func NewTraining() tea.Model {
+	m := initialModel()
+	m.continueTraining()
+	return m
+}
+
+// NewKeyLesson returns a model that starts a key-unlock lesson.
+func NewKeyLesson() tea.Model {
+	m := initialModel()
+	m.startKeyLesson()
+	return m
+}
+
+// NewTest returns a model that starts a timed or word-count test
+// straight away, using the configured language.
+func NewTest(test engine.TestMode) tea.Model {
+	m := initialModel()
+	m.startTest(test)
+	return m
+}
+
+// WithRules overrides the configured strictness rules for the session
+// started by NewPractice, NewTest or NewFile, and for every session after it.
+func WithRules(m tea.Model, rules engine.Rules) tea.Model {
+	mm, ok := m.(model)
+	if !ok {
+		return m
+	}
+	mm.rules = rules
+	if mm.engine != nil {
+		mm.engine.Rules = rules
+	}
+	return mm
+}
+
+var loadPacks sync.Once
+
+// LoadLanguagePacks registers the packs in ~/.kata/languages, overriding
+// built-ins of the same name. Only the first call loads them, so every
+// entry point can make sure they are there; packs that fail to load are
+// reported then and skipped.
+func LoadLanguagePacks() {
+	loadPacks.Do(func() {
+		dir, err := config.GetLanguagesDir()
+		if err == nil {
+			err = generator.LoadPacks(dir)
+		}
+		if err != nil {
+			fmt.Printf("Warning: Could not load language packs: %v\n", err)
+		}
+	})
+}
+
 func initialModel() model {
+	LoadLanguagePacks()
 	gen := generator.New()
 
 	// Load configuration
@@ -43,32 +118,185 @@ func initialModel() model {
 		// Log but don't crash, stats will just be disabled
 		// fmt.Printf("Warning: Could not open database at %s: %v\n", cfg.DBPath, err)
 	}
+	if db != nil {
+		scheduler, err := stats.NewScheduler(cfg.Scheduler)
+		if err != nil {
+			fmt.Printf("Warning: %v, using SM-2\n", err)
+		} else {
+			db.SetScheduler(scheduler)
+		}
+	}
+
+	rules, err := engine.ParseRules(cfg.Rules)
+	if err != nil {
+		fmt.Printf("Warning: %v, using standard rules\n", err)
+	}
 
 	// Load theme from config
 	selectedTheme := themes.GetTheme(cfg.Theme)
 
 	// Set generator language
 	gen.SetLanguage(generator.Language(cfg.Language))
+	gen.TopWords = cfg.TopWords
+	if cfg.Corpus != "" {
+		if text, err := gen.GenerateFromFile(cfg.Corpus); err != nil {
+			fmt.Printf("Warning: Could not load corpus: %v\n", err)
+		} else {
+			gen.SetCorpus(text)
+		}
+	}
 
 	ti := textinput.New()
 	ti.Placeholder = "/path/to/file.txt"
 	ti.CharLimit = 156
 	ti.Width = 40
 
-	return model{
+	m := model{
 		screen:      screenMenu,
 		menuIndex:   0,
-		menuOptions: []string{"Bigrams", "Keywords", "Symbols", "Code Snippets", "Practice Weaknesses", "Load File", "View Stats", "Change Theme", "Change Language", "Toggle Zen Mode", "Quit"},
+		menuOptions: []string{"Continue Training", "Bigrams", "Keywords", "Symbols", "Code Snippets", "Practice Weaknesses", "Unlock Keys", "Speed Test", "Load File", "View Stats", "Change Theme", "Change Language", "Toggle Zen Mode", "Strictness", "Quit"},
 		generator:   gen,
 		db:          db,
 		textInput:   ti,
 		theme:       selectedTheme,
 		themeIndex:  0,
 		config:      cfg,
+		rules:       rules,
+	}
+	m.refreshProgress()
+	m.refreshWeakBigrams()
+	return m
+}
+
+// refreshWeakBigrams points pseudo-word lessons at the weakest bigrams.
+func (m *model) refreshWeakBigrams() {
+	bias := m.config.BigramBias(generator.DefaultWeakBias)
+	if m.db == nil {
+		m.generator.SetWeakBigrams(nil, bias)
+		return
+	}
+	weak, _ := m.db.WeakBigramWeights(10)
+	m.generator.SetWeakBigrams(weak, bias)
+}
+
+// refreshProgress recomputes the curriculum level in the current language.
+func (m *model) refreshProgress() {
+	m.progress = progression.Progress{Language: string(m.generator.Language), Level: progression.Levels[0]}
+	if m.db == nil {
+		return
+	}
+	if p, err := progression.Load(m.db, string(m.generator.Language)); err == nil {
+		m.progress = p
+	}
+}
+
+// startKeyLesson starts a lesson typed with the unlocked keys only,
+// drilling the newest one.
+func (m *model) startKeyLesson() {
+	m.lessonType, m.lessonSource = generator.TypeKeys, ""
+
+	layout := m.config.Layout
+	home, ok := keyboard.HomeRow(layout)
+	if !ok {
+		layout = keyboard.DefaultLayout
+		home, _ = keyboard.HomeRow(layout)
+	}
+
+	keys, focus := strings.Split(home, ""), ""
+	m.keys = progression.KeySet{}
+	if m.db != nil {
+		if k, err := progression.LoadKeys(m.db, layout, string(m.generator.Language)); err == nil {
+			m.keys = k
+			keys, focus = k.Keys, k.Focus()
+		}
+	}
+
+	m.targetText = strings.TrimSpace(m.generator.GenerateFromKeys(keys, focus, 20))
+	m.startPractice()
+}
+
+// startFile cuts a file into chapters and starts the first, unless
+// there is progress through it to resume.
+func (m *model) startFile(path string) error {
+	content, err := m.generator.GenerateFromFile(path)
+	if err != nil {
+		return err
+	}
+	chunks := extract.Chunk(path, content, extract.DefaultOptions)
+	if len(chunks) == 0 {
+		return errors.New("file is empty")
+	}
+
+	m.chunks, m.chunk, m.offset = chunks, 0, 0
+	m.filePath, m.fileHash = path, stats.HashText(content)
+	if abs, err := filepath.Abs(path); err == nil {
+		m.filePath = abs
 	}
+
+	if m.db != nil {
+		p, ok, _ := m.db.GetFileProgress(m.filePath)
+		if ok && p.Hash == m.fileHash && p.Chapter < len(chunks) && (p.Chapter > 0 || p.Offset > 0) {
+			m.resume = p
+			m.screen = screenResume
+			return nil
+		}
+	}
+	m.startChunk()
+	return nil
+}
+
+func (m *model) startChunk() {
+	text := []rune(m.chunks[m.chunk].Text)
+	if m.offset < 0 || m.offset >= len(text) {
+		m.offset = 0
+	}
+	m.lessonType, m.lessonSource = generator.TypeFile, m.chunks[m.chunk].Path
+	m.targetText = string(text[m.offset:])
+	m.startPractice()
+}
+
+// saveFileProgress records where to resume the file: after the chapter if
+// it was passed, otherwise from the start of the line being typed.
+func (m *model) saveFileProgress() {
+	if m.db == nil || m.lessonType != generator.TypeFile || len(m.chunks) == 0 {
+		return
+	}
+
+	p := stats.FileProgress{Path: m.filePath, Hash: m.fileHash, Chapter: m.chunk, Offset: m.offset}
+	switch {
+	case m.engine.IsFinished && m.passed:
+		if !m.hasNextChunk() {
+			m.db.ClearFileProgress(m.filePath)
+			return
+		}
+		p.Chapter, p.Offset = m.chunk+1, 0
+	case !m.engine.IsFinished:
+		if m.engine.StartTime.IsZero() {
+			return
+		}
+		typed := min(m.engine.Cursor(), len(m.engine.TargetText))
+		for i, r := range m.engine.TargetText[:typed] {
+			if r == '\n' {
+				p.Offset = m.offset + i + 1
+			}
+		}
+	}
+	m.db.SaveFileProgress(p)
+}
+
+// continueTraining starts a lesson at the user's current level.
+func (m *model) continueTraining() {
+	level := m.progress.Level
+	m.training = true
+	m.lessonType, m.lessonSource = level.Lesson, ""
+	m.targetText = strings.TrimSpace(m.generator.GenerateLesson(level.Lesson, level.Length))
+	m.startPractice()
 }
 
 func (m *model) generateWeaknessLesson() {
+	m.lessonType = generator.TypeWeaknesses
+	m.lessonSource = ""
+
 	if m.db == nil {
 		m.targetText = m.generator.GenerateLesson(generator.TypeWords, 15)
 		m.targetText = strings.TrimSpace(m.targetText)
@@ -101,6 +329,30 @@ func (m *model) generateWeaknessLesson() {
 		})
 	}
 
+	dueBigrams, err := m.db.GetDueBigrams(5)
+	if err != nil || len(dueBigrams) == 0 {
+		dueBigrams, _ = m.db.GetWeakestBigrams(5)
+	}
+	for _, k := range dueBigrams {
+		total := float64(k.Errors + k.Successes)
+		if total == 0 {
+			continue
+		}
+		weakList = append(weakList, generator.WeakKey{
+			Key:       k.Key,
+			ErrorRate: float64(k.Errors) / total,
+		})
+	}
+
+	confusions, _ := m.db.GetTopConfusions(3)
+	for _, c := range confusions {
+		weakList = append(weakList, generator.WeakKey{
+			Key:          c.Expected,
+			ErrorRate:    c.Rate,
+			ConfusedWith: c.Typed,
+		})
+	}
+
 	m.targetText = m.generator.GenerateWeaknessLesson(weakList, 20)
 	m.targetText = strings.TrimSpace(m.targetText)
 	m.startPractice()
@@ -108,27 +360,123 @@ func (m *model) generateWeaknessLesson() {
 
 func (m *model) startPractice() {
 	m.screen = screenPractice
-	m.engine = engine.New(m.targetText)
+	m.engine = engine.NewTest(m.targetText, m.test)
+	m.engine.Rules = m.rules
+	m.engine.Indent = engine.Indentation{Auto: m.config.AutoIndent, CollapseTrailing: m.config.TrimTrailing}
+	m.record = stats.RecordResult{}
+	m.passed = false
+	m.saveErr = nil
+	m.levelUp = false
+	m.unlocked = ""
+	m.ticking = false
+}
+
+// testChunkWords is how many words a test is generated with, and how many
+// are appended each time a timed test runs low.
+const testChunkWords = 40
+
+func (m *model) startTest(test engine.TestMode) {
+	m.test = test
+	m.lessonType, m.lessonSource = generator.TypeWords, ""
+
+	words := testChunkWords
+	if test.Kind == engine.TestWords {
+		words = test.Words
+	}
+	m.targetText = strings.TrimSpace(m.generator.GenerateLesson(generator.TypeWords, words))
+	m.startPractice()
+}
+
+type tickMsg time.Time
+
+// tickCmd drives the countdown of a timed test.
+func tickCmd() tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
 }
 
 func (m *model) saveSession() {
+	m.passed = m.engine.Passed(m.config.AccuracyGate())
 	if m.db == nil {
 		return
 	}
 
-	wpm, accuracy, duration := m.engine.GetStats()
+	metrics := m.engine.Metrics()
 
 	session := stats.Session{
-		Text:       string(m.engine.TargetText),
-		WPM:        wpm,
-		Accuracy:   accuracy,
-		Duration:   duration,
-		ErrorCount: m.engine.ErrorCount,
+		Text:       m.engine.Typed(),
+		WPM:        metrics.NetWPM,
+		Accuracy:   metrics.Accuracy,
+		Duration:   metrics.Duration,
+		ErrorCount: metrics.UncorrectedErrors,
 		Timestamp:  time.Now(),
+
+		RawWPM:          metrics.RawWPM,
+		CorrectedErrors: metrics.CorrectedErrors,
+		Consistency:     metrics.Consistency,
+
+		Mode:     m.lessonType.String(),
+		Language: string(m.generator.Language),
+		Source:   m.lessonSource,
+		ZenMode:  m.config.ZenMode,
+		Rules:    m.engine.Rules.String(),
+		TestMode: m.engine.Test.String(),
+		Passed:   m.passed,
+
+		Substitutions:  m.engine.Errors.Substitutions,
+		Insertions:     m.engine.Errors.Insertions,
+		Omissions:      m.engine.Errors.Omissions,
+		Transpositions: m.engine.Errors.Transpositions,
+	}
+	if m.lessonSource != "" {
+		session.SourceHash = stats.HashText(session.Text)
+	}
+
+	// Compare against the records before this session becomes one.
+	m.record, _ = m.db.CheckRecord(session)
+
+	keystrokes := convertKeystrokes(m.engine.Keystrokes)
+	if _, err := m.db.SaveSessionWithKeystrokes(session, keystrokes); err != nil {
+		m.saveErr = err
+		m.saveFileProgress()
+		return
 	}
 
-	m.db.SaveSession(session)
+	level := m.progress.Level.Number
+	m.refreshProgress()
+	m.levelUp = m.progress.Level.Number > level
 
-	// Update key statistics for SRS
-	m.db.UpdateKeyStats(string(m.engine.TargetText), string(m.engine.UserInput))
+	// Update key statistics for SRS, leaving out what the engine typed
+	target, input := m.engine.Scored()
+	m.saveErr = errors.Join(
+		m.db.UpdateKeyStatsWithKeystrokes(string(target), string(input), keystrokes),
+		m.db.UpdateBigramStatsWithKeystrokes(string(target), string(input), keystrokes),
+		m.db.UpdateConfusions(string(target), string(input), keystrokes),
+		m.db.RebuildLatencyStats(),
+	)
+	m.refreshWeakBigrams()
+
+	if m.lessonType == generator.TypeKeys && m.passed && m.keys.Layout != "" {
+		m.keys, m.unlocked, _ = progression.AdvanceKeys(m.db, m.keys)
+	}
+	m.saveFileProgress()
+}
+
+func convertKeystrokes(events []engine.Keystroke) []stats.Keystroke {
+	keystrokes := make([]stats.Keystroke, 0, len(events))
+	for _, k := range events {
+		expected := ""
+		if k.Expected != 0 {
+			expected = string(k.Expected)
+		}
+		keystrokes = append(keystrokes, stats.Keystroke{
+			Position:  k.Position,
+			Expected:  expected,
+			Typed:     string(k.Typed),
+			Kind:      k.Kind.String(),
+			Timestamp: k.Time,
+		})
+	}
+	return keystrokes
 }
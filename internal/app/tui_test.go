@@ -0,0 +1,114 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"kata/pkg/engine"
+	"kata/pkg/generator"
+	"kata/pkg/stats"
+)
+
+func TestWithRulesSurvivesRetry(t *testing.T) {
+	// Keep config and stats out of the real home directory.
+	t.Setenv("HOME", t.TempDir())
+
+	rules := engine.Rules{SuddenDeath: true}
+	m := WithRules(NewPractice("abc", generator.TypeWords, ""), rules)
+	defer func() {
+		if db := m.(model).db; db != nil {
+			db.Close()
+		}
+	}()
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	if mm := m.(model); !mm.engine.Failed {
+		t.Fatal("Expected sudden death to end the session")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	mm := m.(model)
+	if mm.engine.IsFinished {
+		t.Fatal("Expected r to retry the session")
+	}
+	if mm.engine.Rules != rules {
+		t.Errorf("Expected the retry to keep %s, got %s", rules, mm.engine.Rules)
+	}
+}
+
+func TestStatsCompareLikeSessions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	m := NewPractice("abc", generator.TypeWords, "").(model)
+	if m.db == nil {
+		t.Fatal("Expected a database")
+	}
+	defer m.db.Close()
+
+	// 40 WPM is below the overall average of 50 but above the 35 of the
+	// other word lessons, which is what it should be measured against.
+	now := time.Now()
+	for i, s := range []stats.Session{
+		{Mode: "words", Language: "english", WPM: 30, Accuracy: 100},
+		{Mode: "code", Language: "go", WPM: 80, Accuracy: 100},
+		{Mode: "words", Language: "english", WPM: 40, Accuracy: 100},
+	} {
+		s.Timestamp = now.Add(time.Duration(i-3) * time.Minute)
+		s.Passed = true
+		if err := m.db.SaveSession(s); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+
+	content := m.buildStatsContent()
+	if !strings.Contains(content, "Average WPM: 35") {
+		t.Errorf("Expected the average of the latest session's label, got\n%s", content)
+	}
+	_, recent, _ := strings.Cut(content, "Recent Sessions:")
+	for _, line := range strings.Split(recent, "\n") {
+		if strings.Contains(line, "WPM: 40 |") && !strings.Contains(line, "↑") {
+			t.Errorf("Expected 40 WPM marked up against its own label, got %q", line)
+		}
+	}
+}
+
+func TestStatsShowHesitationWithoutWeakKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	m := NewPractice("abc", generator.TypeWords, "").(model)
+	if m.db == nil {
+		t.Fatal("Expected a database")
+	}
+	defer m.db.Close()
+
+	// Latency samples but no key stats: no key has been ranked by errors.
+	text := "fjfjfjfjfjfj"
+	start := time.Now()
+	var keystrokes []stats.Keystroke
+	for i, r := range text {
+		keystrokes = append(keystrokes, stats.Keystroke{
+			Position:  i,
+			Expected:  string(r),
+			Typed:     string(r),
+			Kind:      stats.KeystrokeInsert,
+			Timestamp: start.Add(time.Duration(i) * 200 * time.Millisecond),
+		})
+	}
+	if _, err := m.db.SaveSessionWithKeystrokes(stats.Session{Text: text, Timestamp: start, Passed: true}, keystrokes); err != nil {
+		t.Fatalf("SaveSessionWithKeystrokes failed: %v", err)
+	}
+	if err := m.db.RebuildLatencyStats(); err != nil {
+		t.Fatalf("RebuildLatencyStats failed: %v", err)
+	}
+
+	content := m.buildStatsContent()
+	if strings.Contains(content, "Weakest Keys") {
+		t.Fatalf("Expected no weak keys yet, got\n%s", content)
+	}
+	if !strings.Contains(content, "Hesitation") {
+		t.Errorf("Expected the hesitation view without weak keys, got\n%s", content)
+	}
+}
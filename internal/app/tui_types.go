@@ -7,7 +7,9 @@ import (
 
 	"kata/pkg/config"
 	"kata/pkg/engine"
+	"kata/pkg/extract"
 	"kata/pkg/generator"
+	"kata/pkg/progression"
 	"kata/pkg/stats"
 	"kata/pkg/themes"
 )
@@ -27,6 +29,9 @@ const (
 	screenThemeSelect
 	screenLanguageSelect
 	screenLoadFile
+	screenTestSelect
+	screenRulesSelect
+	screenResume
 )
 
 type model struct {
@@ -38,6 +43,44 @@ type model struct {
 	engine     *engine.Engine
 	targetText string // Temporary holder for text before engine start
 
+	// What is being practised, recorded with the session
+	lessonType   generator.LessonType
+	lessonSource string
+
+	// Personal bests beaten by the session just finished, and whether it
+	// cleared the accuracy gate
+	record stats.RecordResult
+	passed bool
+
+	// What went wrong recording the session just finished, if anything
+	saveErr error
+
+	// Shape of the current session and whether its clock is ticking
+	test    engine.TestMode
+	ticking bool
+
+	// Strictness rules applied to new sessions
+	rules engine.Rules
+
+	// Curriculum level in the current language; training is set while
+	// following it through "Continue Training"
+	progress progression.Progress
+	training bool
+	levelUp  bool
+
+	// Key-unlock course, and the key the last lesson unlocked
+	keys     progression.KeySet
+	unlocked string
+
+	// A loaded file, cut into chapters, and the one being typed from
+	// offset, in runes, on. The saved progress is offered on screenResume.
+	chunks   []extract.Snippet
+	chunk    int
+	offset   int
+	filePath string
+	fileHash string
+	resume   stats.FileProgress
+
 	// File loading
 	textInput textinput.Model
 	errMsg    string
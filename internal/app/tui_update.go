@@ -3,12 +3,14 @@ package app
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 
 	"kata/pkg/config"
+	"kata/pkg/engine"
 	"kata/pkg/generator"
 	"kata/pkg/themes"
 )
@@ -34,6 +36,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		return m, nil
+	case tickMsg:
+		return m.handleTick(msg)
 	case tea.KeyMsg:
 		if m.screen == screenStats && m.statsReady {
 			var cmd tea.Cmd
@@ -66,6 +70,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleLanguageSelectInput(msg)
 		case screenLoadFile:
 			return m.handleLoadFileInput(msg)
+		case screenTestSelect:
+			return m.handleTestSelectInput(msg)
+		case screenRulesSelect:
+			return m.handleRulesSelectInput(msg)
+		case screenResume:
+			return m.handleResumeInput(msg)
 		}
 	}
 	return m, nil
@@ -93,28 +103,44 @@ func (m model) handleMenuInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) selectMenuItem() (tea.Model, tea.Cmd) {
+	m.test = engine.TestMode{}
+	m.training = false
+	m.chunks = nil
+
 	switch m.menuIndex {
-	case 0: // Bigrams
+	case 0: // Continue Training
+		m.continueTraining()
+	case 1: // Bigrams
+		m.lessonType, m.lessonSource = generator.TypeBigrams, ""
 		m.targetText = strings.TrimSpace(m.generator.GenerateLesson(generator.TypeBigrams, 20))
 		m.startPractice()
-	case 1: // Keywords
+	case 2: // Keywords
+		m.lessonType, m.lessonSource = generator.TypeWords, ""
 		m.targetText = strings.TrimSpace(m.generator.GenerateLesson(generator.TypeWords, 15))
 		m.startPractice()
-	case 2: // Symbols
+	case 3: // Symbols
+		m.lessonType, m.lessonSource = generator.TypeSymbols, ""
 		m.targetText = strings.TrimSpace(m.generator.GenerateLesson(generator.TypeSymbols, 10))
 		m.startPractice()
-	case 3: // Code Snippets
+	case 4: // Code Snippets
+		m.lessonType, m.lessonSource = generator.TypeCode, ""
 		m.targetText = strings.TrimSpace(m.generator.GenerateLesson(generator.TypeCode, 2))
 		m.startPractice()
-	case 4: // Practice Weaknesses
+	case 5: // Practice Weaknesses
 		m.generateWeaknessLesson()
-	case 5: // Load File
+	case 6: // Unlock Keys
+		m.startKeyLesson()
+	case 7: // Speed Test
+		m.screen = screenTestSelect
+		m.themeIndex = 0
+		return m, nil
+	case 8: // Load File
 		m.screen = screenLoadFile
 		m.textInput.Focus()
 		m.textInput.SetValue("")
 		m.errMsg = ""
 		return m, textinput.Blink
-	case 6: // View Stats
+	case 9: // View Stats
 		m.screen = screenStats
 		m.statsReady = false
 		if m.width > 0 && m.height > 0 {
@@ -124,22 +150,26 @@ func (m model) selectMenuItem() (tea.Model, tea.Cmd) {
 			m.statsReady = true
 		}
 		return m, nil
-	case 7: // Change Theme
+	case 10: // Change Theme
 		m.screen = screenThemeSelect
 		m.themeIndex = 0
 		return m, nil
-	case 8: // Change Language
+	case 11: // Change Language
 		m.screen = screenLanguageSelect
 		// Reuse themeIndex for language list navigation as it's just an int
 		m.themeIndex = 0
 		return m, nil
-	case 9: // Toggle Zen Mode
+	case 12: // Toggle Zen Mode
 		m.config.ZenMode = !m.config.ZenMode
 		if err := config.Save(m.config); err != nil {
 			fmt.Printf("Warning: Could not save config: %v\n", err)
 		}
 		return m, nil
-	case 10: // Quit
+	case 13: // Strictness
+		m.screen = screenRulesSelect
+		m.themeIndex = 0
+		return m, nil
+	case 14: // Quit
 		if m.db != nil {
 			m.db.Close()
 		}
@@ -161,13 +191,35 @@ func (m model) handlePracticeInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.menuIndex = 0
 			return m, nil
 		}
+		// A failed session is offered again, text and all.
+		if msg.String() == "r" && !m.passed {
+			m.startPractice()
+			return m, nil
+		}
+		if msg.String() == "n" && m.passed {
+			if m.training {
+				m.continueTraining()
+				return m, nil
+			}
+			if m.lessonType == generator.TypeKeys {
+				m.startKeyLesson()
+				return m, nil
+			}
+			if m.hasNextChunk() {
+				m.chunk, m.offset = m.chunk+1, 0
+				m.startChunk()
+				return m, nil
+			}
+		}
 		return m, nil
 	}
 
 	switch msg.String() {
 	case "ctrl+c":
+		m.saveFileProgress()
 		return m, tea.Quit
 	case "esc":
+		m.saveFileProgress()
 		m.screen = screenMenu
 		return m, nil
 	case "ctrl+z":
@@ -182,11 +234,82 @@ func (m model) handlePracticeInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Check if just finished
 	if m.engine.IsFinished {
 		m.saveSession()
+		return m, nil
+	}
+
+	// The clock of a timed test starts with the first keystroke.
+	if m.engine.Test.Kind == engine.TestTimed && !m.engine.StartTime.IsZero() && !m.ticking {
+		m.ticking = true
+		return m, tickCmd()
 	}
 
 	return m, nil
 }
 
+func (m model) handleTick(msg tickMsg) (tea.Model, tea.Cmd) {
+	if m.screen != screenPractice || m.engine == nil || m.engine.IsFinished {
+		m.ticking = false
+		return m, nil
+	}
+
+	m.engine.Tick(time.Time(msg))
+	if m.engine.IsFinished {
+		m.ticking = false
+		m.saveSession()
+		return m, nil
+	}
+
+	if m.engine.NeedsMoreText() {
+		m.engine.AppendText(m.generator.GenerateLesson(generator.TypeWords, testChunkWords))
+	}
+
+	return m, tickCmd()
+}
+
+// testOptions lists the choices on the speed test screen.
+func testOptions() []engine.TestMode {
+	var options []engine.TestMode
+	for _, d := range engine.TestDurations {
+		options = append(options, engine.TestMode{Kind: engine.TestTimed, Duration: d})
+	}
+	for _, n := range engine.TestWordCounts {
+		options = append(options, engine.TestMode{Kind: engine.TestWords, Words: n})
+	}
+	return options
+}
+
+func (m model) handleTestSelectInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	options := testOptions()
+	maxIndex := len(options) - 1
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		if m.db != nil {
+			m.db.Close()
+		}
+		return m, tea.Quit
+	case "esc":
+		m.screen = screenMenu
+		return m, nil
+	case "up", "k":
+		if m.themeIndex > 0 {
+			m.themeIndex--
+		} else {
+			m.themeIndex = maxIndex // Wrap to bottom
+		}
+	case "down", "j":
+		if m.themeIndex < maxIndex {
+			m.themeIndex++
+		} else {
+			m.themeIndex = 0 // Wrap to top
+		}
+	case "enter":
+		m.startTest(options[m.themeIndex])
+		return m, nil
+	}
+	return m, nil
+}
+
 func (m model) handleThemeSelectInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	themeNames := themes.ListThemes()
 	maxIndex := len(themeNames) - 1
@@ -232,7 +355,7 @@ func (m model) handleThemeSelectInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) handleLanguageSelectInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	languages := []string{"go", "cpp", "javascript", "rust", "python", "english", "spanish", "french", "german"}
+	languages := generator.Languages()
 	maxIndex := len(languages) - 1
 
 	switch msg.String() {
@@ -262,13 +385,14 @@ func (m model) handleLanguageSelectInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			lang := languages[m.themeIndex]
 
 			// Update generator
-			m.generator.SetLanguage(generator.Language(lang))
+			m.generator.SetLanguage(lang)
 
 			// Save to config
-			m.config.Language = lang
+			m.config.Language = string(lang)
 			if err := config.Save(m.config); err != nil {
 				fmt.Printf("Warning: Could not save config: %v\n", err)
 			}
+			m.refreshProgress()
 		}
 		m.screen = screenMenu
 		return m, nil
@@ -282,20 +406,9 @@ func (m model) handleLoadFileInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.screen = screenMenu
 		return m, nil
 	case tea.KeyEnter:
-		filepath := m.textInput.Value()
-		content, err := m.generator.GenerateFromFile(filepath)
-		if err != nil {
+		if err := m.startFile(m.textInput.Value()); err != nil {
 			m.errMsg = fmt.Sprintf("Error: %v", err)
-			return m, nil
-		}
-
-		if strings.TrimSpace(content) == "" {
-			m.errMsg = "Error: File is empty"
-			return m, nil
 		}
-
-		m.targetText = strings.TrimSpace(content)
-		m.startPractice()
 		return m, nil
 	}
 
@@ -304,6 +417,28 @@ func (m model) handleLoadFileInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m model) handleResumeInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		if m.db != nil {
+			m.db.Close()
+		}
+		return m, tea.Quit
+	case "esc":
+		m.screen = screenMenu
+	case "y", "enter":
+		m.chunk, m.offset = m.resume.Chapter, m.resume.Offset
+		m.startChunk()
+	case "n":
+		if m.db != nil {
+			m.db.ClearFileProgress(m.filePath)
+		}
+		m.chunk, m.offset = 0, 0
+		m.startChunk()
+	}
+	return m, nil
+}
+
 func (m model) handleStatsInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c", "q":
@@ -317,3 +452,40 @@ func (m model) handleStatsInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 	return m, nil
 }
+
+func (m model) handleRulesSelectInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	maxIndex := len(engine.RuleNames) - 1
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		if m.db != nil {
+			m.db.Close()
+		}
+		return m, tea.Quit
+	case "esc":
+		m.config.Rules = m.rules.String()
+		if m.config.Rules == "standard" {
+			m.config.Rules = ""
+		}
+		if err := config.Save(m.config); err != nil {
+			fmt.Printf("Warning: Could not save config: %v\n", err)
+		}
+		m.screen = screenMenu
+		return m, nil
+	case "up", "k":
+		if m.themeIndex > 0 {
+			m.themeIndex--
+		} else {
+			m.themeIndex = maxIndex // Wrap to bottom
+		}
+	case "down", "j":
+		if m.themeIndex < maxIndex {
+			m.themeIndex++
+		} else {
+			m.themeIndex = 0 // Wrap to top
+		}
+	case "enter", " ":
+		m.rules.Toggle(engine.RuleNames[m.themeIndex])
+	}
+	return m, nil
+}
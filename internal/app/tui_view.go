@@ -14,6 +14,12 @@ func (m model) View() string {
 		return m.renderLanguageSelect()
 	case screenLoadFile:
 		return m.renderLoadFile()
+	case screenTestSelect:
+		return m.renderTestSelect()
+	case screenRulesSelect:
+		return m.renderRulesSelect()
+	case screenResume:
+		return m.renderResume()
 	}
 	return ""
 }
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"fmt"
+	"path/filepath"
 	"strings"
 )
 
@@ -24,3 +26,18 @@ func (m model) renderLoadFile() string {
 
 	return b.String()
 }
+
+func (m model) renderResume() string {
+	var b strings.Builder
+
+	b.WriteString(m.theme.Title.Render("📂 Resume File"))
+	b.WriteString("\n\n")
+	b.WriteString(m.theme.Stats.Render(filepath.Base(m.filePath)))
+	b.WriteString("\n\n")
+	b.WriteString(m.theme.Menu.Render(fmt.Sprintf("You stopped at chapter %d of %d on %s.",
+		m.resume.Chapter+1, len(m.chunks), m.resume.UpdatedAt.Format("Jan 02"))))
+	b.WriteString("\n\n")
+	b.WriteString(m.theme.Dim.Render("y/Enter to resume | n to start over | ESC to cancel"))
+
+	return b.String()
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -31,6 +32,10 @@ func (m model) renderMenu() string {
 				displayOption = "Toggle Zen Mode [OFF]"
 			}
 		}
+		if option == "Continue Training" {
+			level := m.progress.Level
+			displayOption = fmt.Sprintf("Continue Training [Level %d: %s]", level.Number, level.Name)
+		}
 
 		b.WriteString(style.Render(cursor + displayOption))
 		b.WriteString("\n")
@@ -3,8 +3,14 @@ package app
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
+
+	"kata/pkg/align"
+	"kata/pkg/engine"
+	"kata/pkg/extract"
+	"kata/pkg/generator"
 )
 
 func (m model) renderPractice() string {
@@ -20,70 +26,99 @@ func (m model) renderPractice() string {
 		b.WriteString("\n\n")
 
 		if m.engine.IsFinished {
-			wpm, accuracy, duration := m.engine.GetStats()
+			metrics := m.engine.Metrics()
 
-			b.WriteString(m.theme.Stats.Render(fmt.Sprintf("✓ Complete!\n\n")))
-			b.WriteString(m.theme.Stats.Render(fmt.Sprintf("Time: %.1f seconds\n", duration)))
-			b.WriteString(m.theme.Stats.Render(fmt.Sprintf("WPM: %.0f\n", wpm)))
-			b.WriteString(m.theme.Stats.Render(fmt.Sprintf("Accuracy: %.1f%%\n", accuracy)))
+			if m.engine.Failed {
+				b.WriteString(m.theme.Incorrect.Render("✗ Sudden death - the session ended on the first mistake\n\n"))
+			} else if !m.passed {
+				b.WriteString(m.theme.Incorrect.Render(fmt.Sprintf("✗ Failed - below the %.0f%% accuracy gate\n\n", m.config.AccuracyGate())))
+			} else {
+				b.WriteString(m.theme.Stats.Render(fmt.Sprintf("✓ Complete!\n\n")))
+			}
+			if test := m.engine.Test.String(); test != "" {
+				b.WriteString(m.theme.Stats.Render(fmt.Sprintf("Test: %s (%d words)\n", test, m.engine.WordsTyped())))
+			}
+			b.WriteString(m.theme.Stats.Render(fmt.Sprintf("Time: %.1f seconds\n", metrics.Duration)))
+			b.WriteString(m.theme.Stats.Render(fmt.Sprintf("WPM: %.0f", metrics.NetWPM)))
+			b.WriteString(m.theme.Dim.Render(fmt.Sprintf(" (raw %.0f)\n", metrics.RawWPM)))
+			b.WriteString(m.theme.Stats.Render(fmt.Sprintf("Accuracy: %.1f%%\n", metrics.Accuracy)))
+			b.WriteString(m.theme.Stats.Render(fmt.Sprintf("Errors: %d corrected, %d uncorrected\n",
+				metrics.CorrectedErrors, metrics.UncorrectedErrors)))
+			b.WriteString(m.theme.Stats.Render(fmt.Sprintf("Consistency: %.0f%%\n", metrics.Consistency)))
 			b.WriteString("\n")
-			b.WriteString(m.theme.Dim.Render("Press Enter to return to menu | q to quit"))
-
-			content = b.String()
-		} else {
-			// Render the text with colors
-			// Wrap text in a container to prevent it from spreading too wide
-			var textBlock strings.Builder
-
-			targetText := m.engine.TargetText
-			userInput := m.engine.UserInput
-
-			for i := 0; i < len(targetText); i++ {
-				if i < len(userInput) {
-					if userInput[i] == targetText[i] {
-						textBlock.WriteString(m.theme.Correct.Render(string(targetText[i])))
-					} else {
-						textBlock.WriteString(m.theme.Incorrect.Render(string(userInput[i])))
-					}
-				} else if i == len(userInput) {
-					textBlock.WriteString(m.theme.Cursor.Render(string(targetText[i])))
-				} else {
-					textBlock.WriteString(m.theme.Dim.Render(string(targetText[i])))
-				}
+			if m.saveErr != nil {
+				b.WriteString(m.theme.Incorrect.Render(fmt.Sprintf("Could not save stats: %v", m.saveErr)))
+				b.WriteString("\n\n")
 			}
-
-			// Show cursor if user typed past the end
-			if len(userInput) >= len(targetText) {
-				textBlock.WriteString(m.theme.Cursor.Render(" "))
+			if banner := m.recordBanner(metrics.NetWPM, metrics.Accuracy); banner != "" {
+				b.WriteString(banner)
+				b.WriteString("\n\n")
 			}
-
-			// Apply a width limit to the text block for better reading
-			// Default width 60, but adapt if screen is smaller
-			textWidth := 60
-			if m.width > 0 && m.width < 70 {
-				textWidth = m.width - 10
+			if m.levelUp {
+				b.WriteString(m.levelBanner())
+				b.WriteString("\n\n")
+			}
+			if m.unlocked != "" {
+				b.WriteString(m.theme.Correct.Render(fmt.Sprintf("🔓 New key unlocked: %s", m.unlocked)))
+				b.WriteString("\n\n")
 			}
-			if textWidth < 20 {
-				textWidth = 20
+			if !m.passed {
+				b.WriteString(m.gateBanner(metrics.Accuracy))
+				b.WriteString("\n\n")
+				b.WriteString(m.theme.Dim.Render("Press r to retry | Enter to return to menu | q to quit"))
+			} else if m.training || m.lessonType == generator.TypeKeys {
+				b.WriteString(m.theme.Dim.Render("Press n for the next lesson | Enter to return to menu | q to quit"))
+			} else if m.hasNextChunk() {
+				b.WriteString(m.theme.Dim.Render("Press n for the next chapter | Enter to return to menu | q to quit"))
+			} else {
+				b.WriteString(m.theme.Dim.Render("Press Enter to return to menu | q to quit"))
 			}
 
+			content = b.String()
+		} else {
+			targetText := m.engine.TargetText
+
+			textWidth := m.textWidth()
 			style := lipgloss.NewStyle().Width(textWidth).Align(lipgloss.Left)
-			b.WriteString(style.Render(textBlock.String()))
+			b.WriteString(style.Render(m.renderText(textWidth, m.height-practiceChrome)))
 
 			b.WriteString("\n\n")
 
 			if !m.engine.StartTime.IsZero() {
 				wpm, _, duration := m.engine.GetStats()
-				progress := float64(len(userInput)) / float64(len(targetText)) * 100.0
-				if progress > 100.0 {
-					progress = 100.0
+				switch m.engine.Test.Kind {
+				case engine.TestTimed:
+					remaining := m.engine.Remaining(time.Now())
+					b.WriteString(m.theme.Dim.Render(fmt.Sprintf("Time left: %.0fs | Errors: %d | WPM: %.0f", remaining.Seconds(), m.engine.ErrorCount, wpm)))
+				case engine.TestWords:
+					b.WriteString(m.theme.Dim.Render(fmt.Sprintf("Words: %d/%d | Time: %.0fs | Errors: %d | WPM: %.0f", m.engine.WordsTyped(), m.engine.Test.Words, duration, m.engine.ErrorCount, wpm)))
+				default:
+					progress := float64(m.engine.Cursor()) / float64(len(targetText)) * 100.0
+					if progress > 100.0 {
+						progress = 100.0
+					}
+					b.WriteString(m.theme.Dim.Render(fmt.Sprintf("Progress: %.0f%% | Time: %.0fs | Errors: %d | WPM: %.0f", progress, duration, m.engine.ErrorCount, wpm)))
 				}
-				b.WriteString(m.theme.Dim.Render(fmt.Sprintf("Progress: %.0f%% | Time: %.0fs | Errors: %d | WPM: %.0f", progress, duration, m.engine.ErrorCount, wpm)))
+			} else if m.engine.Test.Kind == engine.TestTimed {
+				b.WriteString(m.theme.Dim.Render(fmt.Sprintf("%.0f seconds - start typing to begin...", m.engine.Test.Duration.Seconds())))
 			} else {
 				b.WriteString(m.theme.Dim.Render("Start typing to begin..."))
 			}
 
 			b.WriteString("\n\n")
+			if m.lessonType == generator.TypeKeys && len(m.keys.Keys) > 0 {
+				b.WriteString(m.theme.Dim.Render(m.keysLine()))
+				b.WriteString("\n")
+			}
+			if m.lessonType == generator.TypeFile && len(m.chunks) > 1 {
+				c := m.chunks[m.chunk]
+				b.WriteString(m.theme.Dim.Render(fmt.Sprintf("Chapter %d/%d | %s", m.chunk+1, len(m.chunks), c.Source())))
+				b.WriteString("\n")
+			}
+			if rules := m.engine.Rules.String(); rules != "standard" {
+				b.WriteString(m.theme.Dim.Render("Rules: " + rules))
+				b.WriteString("\n")
+			}
 			b.WriteString(m.theme.Dim.Render("ESC to menu | Ctrl+Z to toggle zen | Ctrl+C to quit"))
 
 			content = b.String()
@@ -104,10 +139,39 @@ func (m model) renderPracticeZen() string {
 		wpm, accuracy, _ := m.engine.GetStats()
 
 		b.WriteString("\n\n")
+		if m.engine.Failed {
+			b.WriteString(m.theme.Incorrect.Render("✗ sudden death"))
+			b.WriteString("\n\n")
+		} else if !m.passed {
+			b.WriteString(m.theme.Incorrect.Render(fmt.Sprintf("✗ below %.0f%%", m.config.AccuracyGate())))
+			b.WriteString("\n\n")
+		}
 		b.WriteString(m.theme.Stats.Render(fmt.Sprintf("%.0f WPM", wpm)))
 		b.WriteString("  ")
 		b.WriteString(m.theme.Dim.Render(fmt.Sprintf("%.1f%%", accuracy)))
 		b.WriteString("\n\n")
+		if m.saveErr != nil {
+			b.WriteString(m.theme.Incorrect.Render(fmt.Sprintf("stats not saved: %v", m.saveErr)))
+			b.WriteString("\n\n")
+		}
+		if banner := m.recordBanner(wpm, accuracy); banner != "" {
+			b.WriteString(banner)
+			b.WriteString("\n\n")
+		}
+		if m.levelUp {
+			b.WriteString(m.levelBanner())
+			b.WriteString("\n\n")
+		}
+		if m.unlocked != "" {
+			b.WriteString(m.theme.Correct.Render("🔓 new key: " + m.unlocked))
+			b.WriteString("\n\n")
+		}
+		if !m.passed {
+			b.WriteString(m.gateBanner(accuracy))
+			b.WriteString("\n\n")
+			b.WriteString(m.theme.Dim.Render("r to retry | Enter to continue"))
+			return b.String()
+		}
 		b.WriteString(m.theme.Dim.Render("Press Enter to continue"))
 		return b.String()
 	}
@@ -116,28 +180,212 @@ func (m model) renderPracticeZen() string {
 	b.WriteString("\n\n")
 
 	// Render text
-	targetText := m.engine.TargetText
-	userInput := m.engine.UserInput
+	b.WriteString(m.renderText(m.textWidth(), m.height-zenChrome))
 
-	for i := 0; i < len(targetText); i++ {
-		if i < len(userInput) {
-			if userInput[i] == targetText[i] {
-				b.WriteString(m.theme.Correct.Render(string(targetText[i])))
-			} else {
-				b.WriteString(m.theme.Incorrect.Render(string(userInput[i])))
-			}
-		} else if i == len(userInput) {
-			b.WriteString(m.theme.Cursor.Render(string(targetText[i])))
-		} else {
-			b.WriteString(m.theme.Dim.Render(string(targetText[i])))
+	b.WriteString("\n\n")
+
+	return b.String()
+}
+
+// recordBanner announces the personal bests beaten by the finished session.
+func (m model) recordBanner(wpm, accuracy float64) string {
+	var parts []string
+	if m.record.NewWPM {
+		parts = append(parts, fmt.Sprintf("%.0f WPM (+%.1f)", wpm, m.record.WPMDelta(wpm)))
+	}
+	if m.record.NewAccuracy {
+		parts = append(parts, fmt.Sprintf("%.1f%% accuracy (+%.1f)", accuracy, accuracy-m.record.PreviousAccuracy))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return m.theme.Correct.Render("🏆 New personal best: " + strings.Join(parts, ", "))
+}
+
+// gateBanner explains why a session failed the accuracy gate.
+func (m model) gateBanner(accuracy float64) string {
+	if m.engine.Failed {
+		return m.theme.Incorrect.Render("Failed: the first mistake ends a sudden-death session. Try it again.")
+	}
+	return m.theme.Incorrect.Render(fmt.Sprintf("Failed: %.1f%% accuracy is below the %.0f%% gate. Slow down and try again.",
+		accuracy, m.config.AccuracyGate()))
+}
+
+// levelBanner announces a newly reached curriculum level.
+func (m model) levelBanner() string {
+	level := m.progress.Level
+	return m.theme.Correct.Render(fmt.Sprintf("⬆ Level %d unlocked: %s", level.Number, level.Name))
+}
+
+// keysLine shows the unlocked keys, the one being drilled and the next.
+func (m model) keysLine() string {
+	line := "Keys: " + strings.Join(m.keys.Keys, "")
+	if focus := m.keys.Focus(); focus != "" {
+		line += " | focus: " + focus
+	}
+	if len(m.keys.Locked) > 0 {
+		line += " | next: " + m.keys.Locked[0]
+	}
+	return line
+}
+
+// hasNextChunk reports whether the file being typed has chunks left.
+func (m model) hasNextChunk() bool {
+	return m.lessonType == generator.TypeFile && m.chunk+1 < len(m.chunks)
+}
+
+// textWidth limits the text block for better reading: 60 columns, or less
+// if the screen is smaller.
+func (m model) textWidth() int {
+	width := 60
+	if m.width > 0 && m.width < 70 {
+		width = m.width - 10
+	}
+	return max(width, 20)
+}
+
+// Lines around the text on the practice screen, in normal and zen mode.
+const (
+	practiceChrome = 14
+	zenChrome      = 6
+)
+
+// renderText renders the target text wrapped to width, colouring it from
+// the same alignment the errors are counted from: a skipped or extra rune
+// is one mistake, not a shift of the rest of the line. Extra runes are
+// shown where they were typed. Text taller than rows scrolls to keep the
+// cursor's line a third of the way down; rows <= 0 shows it all.
+func (m model) renderText(width, rows int) string {
+	target := m.engine.TargetText
+	cursor := m.engine.Cursor()
+
+	// What was typed for each target rune: correct, wrong (shown as
+	// typed) or nothing yet, and the extra runes typed before it.
+	const (
+		untyped = iota
+		correct
+		wrong
+	)
+	state := make([]int, len(target))
+	shown := make([]rune, len(target))
+	extra := make(map[int][]rune)
+	input := m.engine.UserInput
+	for _, ed := range m.engine.Edits() {
+		switch ed.Op {
+		case align.Match:
+			state[ed.TargetPos] = correct
+		case align.Substitution:
+			state[ed.TargetPos], shown[ed.TargetPos] = wrong, ed.Typed
+		case align.Omission:
+			state[ed.TargetPos], shown[ed.TargetPos] = wrong, ed.Expected
+		case align.Transposition:
+			state[ed.TargetPos], shown[ed.TargetPos] = wrong, input[ed.InputPos]
+			state[ed.TargetPos+1], shown[ed.TargetPos+1] = wrong, input[ed.InputPos+1]
+		case align.Insertion:
+			extra[ed.TargetPos] = append(extra[ed.TargetPos], ed.Typed)
 		}
 	}
 
-	if len(userInput) >= len(targetText) {
-		b.WriteString(m.theme.Cursor.Render(" "))
+	lines := wrapRows(target, width)
+	first, last := 0, len(lines)
+	if rows > 0 && len(lines) > rows {
+		rows = max(rows, 3)
+		current := len(lines) - 1
+		for i, l := range lines {
+			if cursor < l[1] {
+				current = i
+				break
+			}
+		}
+		first = min(max(current-rows/3, 0), len(lines)-rows)
+		last = first + rows
 	}
 
-	b.WriteString("\n\n")
+	var b strings.Builder
+	for n, l := range lines[first:last] {
+		if n > 0 {
+			b.WriteString("\n")
+		}
+		for i := l[0]; i < l[1]; i++ {
+			for _, r := range extra[i] {
+				b.WriteString(m.theme.Incorrect.Render(displayRune(r, true)))
+			}
+			switch {
+			case state[i] == correct:
+				b.WriteString(m.theme.Correct.Render(displayRune(target[i], false)))
+			case state[i] == wrong:
+				b.WriteString(m.theme.Incorrect.Render(displayRune(shown[i], true)))
+			case i == cursor:
+				b.WriteString(m.theme.Cursor.Render(displayRune(target[i], true)))
+			default:
+				b.WriteString(m.theme.Dim.Render(displayRune(target[i], false)))
+			}
+		}
+	}
 
+	// Show what was typed past the end, and the cursor after it
+	if last == len(lines) {
+		for _, r := range extra[len(target)] {
+			b.WriteString(m.theme.Incorrect.Render(displayRune(r, true)))
+		}
+		if cursor >= len(target) {
+			b.WriteString(m.theme.Cursor.Render(" "))
+		}
+	}
 	return b.String()
 }
+
+// displayRune is how a rune of the text is drawn: tabs as spaces and line
+// breaks as nothing, or as a visible mark where the cursor or a mistake
+// has to show.
+func displayRune(r rune, visible bool) string {
+	switch r {
+	case '\n':
+		if visible {
+			return "↵"
+		}
+		return ""
+	case '\t':
+		return strings.Repeat(" ", extract.TabWidth)
+	}
+	return string(r)
+}
+
+// wrapRows splits text into display rows of at most width columns,
+// breaking at line breaks and, where it can, after spaces. Each row is a
+// [start, end) range of rune indices; a line break belongs to the row it
+// ends.
+func wrapRows(text []rune, width int) [][2]int {
+	runeWidth := func(r rune) int {
+		if r == '\t' {
+			return extract.TabWidth
+		}
+		return 1
+	}
+
+	var rows [][2]int
+	start, col, space := 0, 0, -1
+	for i, r := range text {
+		if r == '\n' {
+			rows = append(rows, [2]int{start, i + 1})
+			start, col, space = i+1, 0, -1
+			continue
+		}
+		if w := runeWidth(r); col+w > width && i > start {
+			end := i
+			if space >= start {
+				end = space + 1
+			}
+			rows = append(rows, [2]int{start, end})
+			start, col, space = end, 0, -1
+			for _, r := range text[start:i] {
+				col += runeWidth(r)
+			}
+		}
+		col += runeWidth(r)
+		if r == ' ' {
+			space = i
+		}
+	}
+	return append(rows, [2]int{start, len(text)})
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 
+	"kata/pkg/engine"
+	"kata/pkg/generator"
 	"kata/pkg/themes"
 )
 
@@ -86,9 +88,7 @@ func (m model) renderLanguageSelect() string {
 	b.WriteString(m.theme.Dim.Render("Choose the vocabulary for your practice:"))
 	b.WriteString("\n\n")
 
-	languages := []string{"go", "cpp", "javascript", "rust", "python", "english", "spanish", "french", "german"}
-
-	for i, lang := range languages {
+	for i, lang := range generator.Languages() {
 		cursor := "  "
 		style := m.theme.Menu
 		if i == m.themeIndex {
@@ -98,11 +98,11 @@ func (m model) renderLanguageSelect() string {
 
 		// Add active indicator
 		active := ""
-		if lang == m.config.Language {
+		if string(lang) == m.config.Language {
 			active = " " + m.theme.Correct.Render("(current)")
 		}
 
-		b.WriteString(style.Render(fmt.Sprintf("%s%-10s%s", cursor, strings.Title(lang), active)))
+		b.WriteString(style.Render(fmt.Sprintf("%s%-10s%s", cursor, strings.Title(string(lang)), active)))
 		b.WriteString("\n")
 	}
 
@@ -111,3 +111,73 @@ func (m model) renderLanguageSelect() string {
 
 	return b.String()
 }
+
+func (m model) renderTestSelect() string {
+	var b strings.Builder
+
+	b.WriteString(m.theme.Title.Render("⏱ Speed Test"))
+	b.WriteString("\n\n")
+	b.WriteString(m.theme.Dim.Render("Race the clock or a fixed number of words:"))
+	b.WriteString("\n\n")
+
+	for i, opt := range testOptions() {
+		cursor := "  "
+		style := m.theme.Menu
+		if i == m.themeIndex {
+			cursor = "▶ "
+			style = m.theme.Selected
+		}
+
+		label := fmt.Sprintf("%d seconds", int(opt.Duration.Seconds()))
+		if opt.Kind == engine.TestWords {
+			label = fmt.Sprintf("%d words", opt.Words)
+		}
+
+		b.WriteString(style.Render(cursor + label))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.theme.Dim.Render("↑/↓ or j/k to navigate | Enter to start | ESC to cancel"))
+
+	return b.String()
+}
+
+func (m model) renderRulesSelect() string {
+	var b strings.Builder
+
+	b.WriteString(m.theme.Title.Render("⚔ Strictness"))
+	b.WriteString("\n\n")
+	b.WriteString(m.theme.Dim.Render("Punish mashing: combine any of these rules."))
+	b.WriteString("\n\n")
+
+	descriptions := map[string]string{
+		engine.RuleStopOnLetter: "the cursor waits for the right key",
+		engine.RuleStopOnWord:   "fix the word before pressing space",
+		engine.RuleNoBackspace:  "no corrections",
+		engine.RuleSuddenDeath:  "the first mistake ends the session",
+	}
+
+	for i, name := range engine.RuleNames {
+		cursor := "  "
+		style := m.theme.Menu
+		if i == m.themeIndex {
+			cursor = "▶ "
+			style = m.theme.Selected
+		}
+
+		check := "[ ]"
+		if m.rules.Has(name) {
+			check = "[x]"
+		}
+
+		b.WriteString(style.Render(fmt.Sprintf("%s%s %-15s", cursor, check, name)))
+		b.WriteString(m.theme.Dim.Render(" " + descriptions[name]))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.theme.Dim.Render("↑/↓ or j/k to navigate | Space/Enter to toggle | ESC to save"))
+
+	return b.String()
+}
@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/guptarohit/asciigraph"
 	"golang.org/x/term"
 
+	"kata/pkg/calendar"
 	"kata/pkg/keyboard"
+	"kata/pkg/progression"
+	"kata/pkg/stats"
 )
 
 func (m model) buildStatsContent() string {
@@ -28,12 +32,28 @@ func (m model) buildStatsContent() string {
 	}
 	separator := m.theme.Separator.Render(strings.Repeat("─", termWidth))
 
-	sessions, err := m.db.GetSessionsForGraph(20)
-	if err != nil || len(sessions) == 0 {
+	latest, err := m.db.GetRecentSessions(1)
+	if err != nil || len(latest) == 0 {
 		b.WriteString(m.theme.Dim.Render("No session data yet. Complete some practice sessions!"))
 		return b.String()
 	}
 
+	// Graph only sessions comparable to the latest one; mixing lesson
+	// types would make the trend meaningless.
+	sessions, err := m.db.QuerySessions(stats.SessionFilter{
+		Mode:     latest[0].Mode,
+		Language: latest[0].Language,
+		TestMode: latest[0].TestMode,
+		Limit:    20,
+	})
+	if err != nil || len(sessions) == 0 {
+		sessions = latest
+	}
+	for i, j := 0, len(sessions)-1; i < j; i, j = i+1, j-1 {
+		sessions[i], sessions[j] = sessions[j], sessions[i]
+	}
+	graphLabel := latest[0].Label()
+
 	b.WriteString(m.theme.Stats.Render("📈 WPM Progress Over Time:"))
 	b.WriteString("\n")
 
@@ -45,17 +65,68 @@ func (m model) buildStatsContent() string {
 	graph := asciigraph.Plot(wpmData,
 		asciigraph.Height(8),
 		asciigraph.Width(60),
-		asciigraph.Caption(fmt.Sprintf("Last %d sessions", len(sessions))))
+		asciigraph.Caption(fmt.Sprintf("Last %d sessions · %s", len(sessions), graphLabel)))
 
 	b.WriteString(m.theme.Correct.Render(graph))
 	b.WriteString("\n")
 	b.WriteString(separator)
 	b.WriteString("\n\n")
 
-	avgWPM, _ := m.db.GetAverageWPM()
-	if avgWPM > 0 {
-		b.WriteString(m.theme.Stats.Render(fmt.Sprintf("Average WPM: %.0f", avgWPM)))
+	// Averages are per label too: a session is only measured against
+	// sessions like it.
+	byMode, err := m.db.AggregateSessions(stats.SessionFilter{}, stats.GroupMode, nil)
+	avgWPM := make(map[string]float64, len(byMode))
+	for _, a := range byMode {
+		avgWPM[a.Group] = a.MeanWPM
+	}
+
+	if avg := avgWPM[graphLabel]; avg > 0 {
+		b.WriteString(m.theme.Stats.Render(fmt.Sprintf("Average WPM: %.0f", avg)))
+		b.WriteString(" " + m.theme.Dim.Render(graphLabel))
+		b.WriteString("\n")
+		b.WriteString(separator)
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString(m.buildHabitContent(termWidth))
+	b.WriteString(separator)
+	b.WriteString("\n\n")
+
+	if err == nil && len(byMode) > 1 {
+		b.WriteString(m.theme.Stats.Render("🗂  By Lesson Type:"))
 		b.WriteString("\n")
+		for _, a := range byMode {
+			b.WriteString(fmt.Sprintf("  %-24s %s WPM: %.0f (median %.0f, best %.0f) | Acc: %.1f%%\n",
+				a.Group,
+				m.theme.Dim.Render(fmt.Sprintf("%4d sessions, %d failed", a.Count, a.Failed)),
+				a.MeanWPM, a.MedianWPM, a.BestWPM, a.MeanAccuracy))
+		}
+		b.WriteString(separator)
+		b.WriteString("\n\n")
+	}
+
+	level := m.progress.Level
+	b.WriteString(m.theme.Stats.Render(fmt.Sprintf("🧗 Level %d/%d: %s", level.Number, len(progression.Levels), level.Name)))
+	b.WriteString("\n")
+	if next, ok := m.progress.Next(); ok {
+		b.WriteString(fmt.Sprintf("  Next: %s %s\n", next.Name, m.theme.Dim.Render("("+m.progress.Status()+")")))
+	}
+	b.WriteString(separator)
+	b.WriteString("\n\n")
+
+	records, err := m.db.GetPersonalBests()
+	if err == nil && len(records) > 0 {
+		b.WriteString(m.theme.Stats.Render("🏆 Personal Bests:"))
+		b.WriteString("\n")
+		for i, r := range records {
+			if i >= 8 {
+				break
+			}
+			b.WriteString(fmt.Sprintf("  %-24s %-7s WPM: %.0f | Acc: %.1f%% %s\n",
+				stats.ModeLabel(r.Mode, r.Language), r.Length,
+				r.BestWPM, r.BestAccuracy,
+				m.theme.Dim.Render(r.BestWPMAt.Format("Jan 02"))))
+		}
 		b.WriteString(separator)
 		b.WriteString("\n\n")
 	}
@@ -71,7 +142,7 @@ func (m model) buildStatsContent() string {
 	accGraph := asciigraph.Plot(accData,
 		asciigraph.Height(6),
 		asciigraph.Width(60),
-		asciigraph.Caption("Accuracy %"))
+		asciigraph.Caption("Accuracy % · "+graphLabel))
 
 	b.WriteString(menuStyle.Render(accGraph))
 	b.WriteString("\n")
@@ -101,14 +172,7 @@ func (m model) buildStatsContent() string {
 
 			errorRate := float64(k.Errors) / float64(total) * 100.0
 
-			keyDisplay := k.Key
-			if k.Key == "\n" {
-				keyDisplay = "↵"
-			} else if k.Key == "\t" {
-				keyDisplay = "⭾"
-			} else if k.Key == " " {
-				keyDisplay = "␣"
-			}
+			keyDisplay := displayKey(k.Key)
 
 			barLength := int(float64(k.Errors) / float64(maxErrors) * 30)
 			if barLength < 0 {
@@ -126,12 +190,76 @@ func (m model) buildStatsContent() string {
 				k.Errors))
 		}
 		b.WriteString("\n")
+
 		b.WriteString(m.theme.Correct.Render("💡 Tip: Use 'Practice Weaknesses' to improve!"))
 		b.WriteString("\n")
 		b.WriteString(separator)
 		b.WriteString("\n")
 	}
 
+	// Hesitation comes from the keystroke log, so it is shown even before
+	// any key has enough attempts to rank by errors.
+	slowKeys, err := m.db.GetSlowestKeys(5)
+	if err == nil && len(slowKeys) > 0 {
+		b.WriteString("\n")
+		b.WriteString(m.theme.Stats.Render("🐢 Hesitation (ms before the key):"))
+		b.WriteString("\n")
+
+		maxLatency := slowKeys[0].P90Ms
+		for _, k := range slowKeys {
+			if k.P90Ms > maxLatency {
+				maxLatency = k.P90Ms
+			}
+		}
+
+		for _, k := range slowKeys {
+			barLength := int(k.MeanMs / maxLatency * 30)
+			if barLength < 1 {
+				barLength = 1
+			}
+			b.WriteString(fmt.Sprintf("  '%s' %s %.0fms (p90 %.0fms)\n",
+				displayKey(k.Key),
+				m.theme.Stats.Render(strings.Repeat("█", barLength)),
+				k.MeanMs,
+				k.P90Ms))
+		}
+		b.WriteString(separator)
+		b.WriteString("\n")
+	}
+
+	weakBigrams, err := m.db.GetWeakestBigrams(5)
+	if err == nil && len(weakBigrams) > 0 {
+		b.WriteString("\n")
+		b.WriteString(m.theme.Incorrect.Render("🔗 Your Weakest Bigrams:"))
+		b.WriteString("\n")
+		for _, k := range weakBigrams {
+			total := k.Errors + k.Successes
+			errorRate := float64(k.Errors) / float64(total) * 100.0
+			b.WriteString(fmt.Sprintf("  %q %.0f%% (%d/%d)\n", k.Key, errorRate, k.Errors, total))
+		}
+		b.WriteString(separator)
+		b.WriteString("\n")
+	}
+
+	confusions, err := m.db.GetTopConfusions(5)
+	if err == nil && len(confusions) > 0 {
+		b.WriteString("\n")
+		b.WriteString(m.theme.Incorrect.Render("🔀 Top Confusions (expected → typed):"))
+		b.WriteString("\n")
+		for _, c := range confusions {
+			rate := ""
+			if c.Rate > 0 {
+				rate = m.theme.Dim.Render(fmt.Sprintf(" (%.0f%% of attempts)", c.Rate*100))
+			}
+			b.WriteString(fmt.Sprintf("  '%s' → '%s' %3d×%s\n",
+				displayKey(c.Expected),
+				m.theme.Incorrect.Render(displayKey(c.Typed)),
+				c.Count, rate))
+		}
+		b.WriteString(separator)
+		b.WriteString("\n")
+	}
+
 	dueKeys, err := m.db.GetDueKeys(100)
 	if err == nil {
 		b.WriteString("\n")
@@ -154,15 +282,20 @@ func (m model) buildStatsContent() string {
 		for _, s := range recentSessions {
 			timeStr := s.Timestamp.Format("Jan 02 15:04")
 			wpmIndicator := "→"
-			if avgWPM > 0 {
-				if s.WPM >= avgWPM {
+			if avg := avgWPM[s.Label()]; avg > 0 {
+				if s.WPM >= avg {
 					wpmIndicator = m.theme.Correct.Render("↑")
 				} else {
 					wpmIndicator = m.theme.Incorrect.Render("↓")
 				}
 			}
-			b.WriteString(fmt.Sprintf("  %s %s WPM: %.0f | Acc: %.1f%%\n",
-				m.theme.Dim.Render(timeStr), wpmIndicator, s.WPM, s.Accuracy))
+			result := ""
+			if !s.Passed {
+				result = " " + m.theme.Incorrect.Render("failed")
+			}
+			b.WriteString(fmt.Sprintf("  %s %s WPM: %.0f | Acc: %.1f%% %s%s\n",
+				m.theme.Dim.Render(timeStr), wpmIndicator, s.WPM, s.Accuracy,
+				m.theme.Dim.Render(s.Label()), result))
 		}
 	}
 
@@ -180,6 +313,67 @@ func (m model) buildStatsContent() string {
 	return b.String()
 }
 
+// buildHabitContent renders streaks, recent practice time and the
+// practice calendar.
+func (m model) buildHabitContent(termWidth int) string {
+	var b strings.Builder
+
+	loc := m.config.Location()
+	now := time.Now()
+
+	streaks, err := m.db.GetStreaks(now, loc)
+	if err != nil {
+		return ""
+	}
+
+	weeks := (termWidth - 6) / 2
+	if weeks > 26 {
+		weeks = 26
+	}
+	if weeks < 8 {
+		weeks = 8
+	}
+
+	days, err := m.db.GetDailyActivity(now.AddDate(0, 0, -7*weeks+1), now, loc)
+	if err != nil || len(days) == 0 {
+		return ""
+	}
+
+	today := days[len(days)-1].Seconds
+	week := 0.0
+	for _, d := range days[max(0, len(days)-7):] {
+		week += d.Seconds
+	}
+
+	b.WriteString(m.theme.Stats.Render(fmt.Sprintf("🔥 Streak: %d days (longest %d)", streaks.Current, streaks.Longest)))
+	b.WriteString(m.theme.Dim.Render(fmt.Sprintf(" | Today: %s | Last 7 days: %s",
+		formatPracticeTime(today), formatPracticeTime(week))))
+	b.WriteString("\n\n")
+	b.WriteString(calendar.Render(days))
+
+	return b.String()
+}
+
+func formatPracticeTime(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
+
+func displayKey(key string) string {
+	switch key {
+	case "\n":
+		return "↵"
+	case "\t":
+		return "⭾"
+	case " ":
+		return "␣"
+	}
+	return key
+}
+
 func (m model) renderStats() string {
 	if !m.statsReady {
 		return m.theme.Dim.Render("Loading stats...\n\nPress ESC or Enter to return to menu")
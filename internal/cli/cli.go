@@ -2,16 +2,23 @@ package cli
 
 import (
 	"fmt"
+	"math/rand"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"kata/internal/app"
+	"kata/pkg/calendar"
 	"kata/pkg/config"
+	"kata/pkg/engine"
 	"kata/pkg/export"
+	"kata/pkg/extract"
 	"kata/pkg/generator"
+	"kata/pkg/keyboard"
+	"kata/pkg/progression"
 	"kata/pkg/stats"
 )
 
@@ -21,12 +28,19 @@ func Run(args []string) {
 	var (
 		showStats    = false
 		setTheme     = ""
+		setLayout    = ""
+		setLanguage  = ""
+		listLangs    = false
 		enableZen    = false
 		practiceMode = ""
 		practiceFile = ""
+		practiceArg  = ""
+		rules        *engine.Rules
 		showHelp     = false
 		exportFormat = ""
 		exportOutput = ""
+		filter       stats.SessionFilter
+		groupBy      = stats.GroupMode
 	)
 
 	// Simple flag parsing
@@ -40,6 +54,18 @@ func Run(args []string) {
 				setTheme = args[i+1]
 				i++
 			}
+		case "--language", "-l":
+			if i+1 < len(args) {
+				setLanguage = args[i+1]
+				i++
+			}
+		case "languages":
+			listLangs = true
+		case "--layout":
+			if i+1 < len(args) {
+				setLayout = args[i+1]
+				i++
+			}
 		case "--zen", "-z":
 			enableZen = true
 		case "--file", "-f":
@@ -61,6 +87,101 @@ func Run(args []string) {
 				practiceMode = args[i+1]
 				i++
 			}
+			if (practiceMode == "time" || practiceMode == "words" || practiceMode == "repo") && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				practiceArg = args[i+1]
+				i++
+			}
+		case "--mode":
+			if i+1 < len(args) {
+				filter.Mode = args[i+1]
+				i++
+			}
+		case "--lang":
+			if i+1 < len(args) {
+				filter.Language = args[i+1]
+				i++
+			}
+		case "--rules":
+			if i+1 < len(args) {
+				r, err := engine.ParseRules(args[i+1])
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				rules = &r
+				i++
+			}
+		case "--result":
+			if i+1 < len(args) {
+				switch args[i+1] {
+				case "passed":
+					filter.Result = stats.ResultPassed
+				case "failed":
+					filter.Result = stats.ResultFailed
+				default:
+					fmt.Printf("Unknown result: %s (use passed or failed)\n", args[i+1])
+					os.Exit(1)
+				}
+				i++
+			}
+		case "--test":
+			if i+1 < len(args) {
+				if _, err := engine.ParseTestMode(args[i+1]); err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				filter.TestMode = args[i+1]
+				i++
+			}
+		case "--from", "--to":
+			if i+1 < len(args) {
+				day, err := time.ParseInLocation("2006-01-02", args[i+1], time.Local)
+				if err != nil {
+					fmt.Printf("Invalid date %q (expected YYYY-MM-DD)\n", args[i+1])
+					os.Exit(1)
+				}
+				if arg == "--from" {
+					filter.From = day
+				} else {
+					filter.To = day.AddDate(0, 0, 1)
+				}
+				i++
+			}
+		case "--days":
+			if i+1 < len(args) {
+				days, err := strconv.Atoi(args[i+1])
+				if err != nil || days <= 0 {
+					fmt.Printf("Invalid number of days: %s\n", args[i+1])
+					os.Exit(1)
+				}
+				filter.From = time.Now().AddDate(0, 0, -days)
+				i++
+			}
+		case "--min-duration":
+			if i+1 < len(args) {
+				seconds, err := strconv.ParseFloat(args[i+1], 64)
+				if err != nil {
+					fmt.Printf("Invalid duration: %s\n", args[i+1])
+					os.Exit(1)
+				}
+				filter.MinDuration = seconds
+				i++
+			}
+		case "--group":
+			if i+1 < len(args) {
+				switch args[i+1] {
+				case "day":
+					groupBy = stats.GroupDay
+				case "week":
+					groupBy = stats.GroupWeek
+				case "mode":
+					groupBy = stats.GroupMode
+				default:
+					fmt.Printf("Unknown grouping: %s (use day, week or mode)\n", args[i+1])
+					os.Exit(1)
+				}
+				i++
+			}
 		case "--help", "-h", "help":
 			showHelp = true
 		}
@@ -78,6 +199,29 @@ func Run(args []string) {
 		cfg = config.DefaultConfig()
 	}
 
+	// Language packs extend the built-in languages.
+	app.LoadLanguagePacks()
+
+	if listLangs {
+		printLanguages(cfg.Language)
+		return
+	}
+
+	// Handle --language
+	if setLanguage != "" {
+		if _, ok := generator.LookupPack(generator.Language(setLanguage)); !ok {
+			fmt.Printf("Unknown language: %s (see kata languages)\n", setLanguage)
+			os.Exit(1)
+		}
+		cfg.Language = setLanguage
+		if err := config.Save(cfg); err != nil {
+			fmt.Printf("Error saving language: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Language set to: %s\n", setLanguage)
+		return
+	}
+
 	// Handle --theme
 	if setTheme != "" {
 		cfg.Theme = setTheme
@@ -89,6 +233,21 @@ func Run(args []string) {
 		return
 	}
 
+	// Handle --layout
+	if setLayout != "" {
+		if _, ok := keyboard.HomeRow(setLayout); !ok {
+			fmt.Printf("Unknown layout: %s (use %s)\n", setLayout, strings.Join(keyboard.Layouts(), ", "))
+			os.Exit(1)
+		}
+		cfg.Layout = setLayout
+		if err := config.Save(cfg); err != nil {
+			fmt.Printf("Error saving layout: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Layout set to: %s\n", setLayout)
+		return
+	}
+
 	// Handle --zen
 	if enableZen {
 		cfg.ZenMode = true
@@ -99,25 +258,45 @@ func Run(args []string) {
 
 	// Handle export
 	if exportFormat != "" {
-		handleExport(exportFormat, exportOutput)
+		handleExport(exportFormat, exportOutput, filter)
 		return
 	}
 
 	// Handle --stats
 	if showStats {
-		printStats()
+		printStats(filter, groupBy)
 		return
 	}
 
 	// Handle practice mode
 	if practiceMode != "" {
-		runPracticeMode(practiceMode)
+		if practiceMode == "continue" || practiceMode == "keys" {
+			m := app.NewTraining()
+			if practiceMode == "keys" {
+				m = app.NewKeyLesson()
+			}
+			p := tea.NewProgram(withRules(m, rules))
+			if _, err := p.Run(); err != nil {
+				fmt.Printf("Error: %v", err)
+				os.Exit(1)
+			}
+			return
+		}
+		if practiceMode == "time" || practiceMode == "words" {
+			runTest(practiceMode, practiceArg, rules)
+			return
+		}
+		if practiceMode == "repo" {
+			runPracticeRepo(practiceArg, rules)
+			return
+		}
+		runPracticeMode(practiceMode, rules)
 		return
 	}
 
 	// Handle practice from file
 	if practiceFile != "" {
-		runPracticeFromFile(practiceFile)
+		runPracticeFromFile(practiceFile, rules)
 		return
 	}
 
@@ -138,17 +317,45 @@ USAGE:
 OPTIONS:
     --stats, -s              Show statistics and exit
     --theme, -t <name>       Set theme (default, catppuccin, rose-pine, dracula, nord, gruvbox)
+    --language, -l <name>    Set practice language (see: kata languages)
+    --layout <name>          Set keyboard layout for key unlocking
+                            (qwerty, qwertz, azerty, dvorak, colemak)
     --zen, -z                Enable zen mode
     --help, -h               Show this help
 
 COMMANDS:
     practice <mode>          Start practice directly
                             Modes: bigrams, keywords, symbols, code, weaknesses
+    practice continue        Next lesson of your curriculum level
+    practice keys            Key-unlock lesson, home row first
+    practice time [secs]     Timed test (default 30 seconds)
+    practice words [n]       Word-count test (default 25 words)
+    practice repo [dir]      Functions, structs and error handling from a
+                            Go module (default: current directory)
     export <format> <file>   Export statistics to file
                             Formats: json, csv
+    languages                List languages, including packs loaded
+                            from ~/.kata/languages (*.yaml, *.json)
 
 OPTIONS WITH FILES:
-    --file, -f <path>        Practice with custom file content
+    --file, -f <path>        Practice with custom file content, one
+                            function- or block-sized chunk at a time
+
+STRICTNESS (for practice and --file):
+    --rules <list>           Comma-separated rules for this session, overriding
+                            the config: stop-on-letter, stop-on-word,
+                            no-backspace, sudden-death (or standard)
+
+FILTERS (for --stats and export):
+    --mode <mode>            Only sessions of this lesson type (bigrams, keywords, ...)
+    --lang <language>        Only sessions in this language
+    --test <time:N|words:N>  Only tests of this shape
+    --result <passed|failed> Only sessions that passed or failed the accuracy gate
+    --from <YYYY-MM-DD>      Only sessions on or after this day
+    --to <YYYY-MM-DD>        Only sessions on or before this day
+    --days <n>               Only sessions from the last n days
+    --min-duration <secs>    Skip sessions shorter than this
+    --group <day|week|mode>  How --stats groups its summary (default: mode)
 
 EXAMPLES:
     kata                     Start interactive mode
@@ -156,16 +363,20 @@ EXAMPLES:
     kata --theme dracula     Set theme to dracula
     kata --zen               Start with zen mode enabled
     kata practice bigrams    Practice bigrams directly
+    kata practice time 60    Type for 60 seconds
+    kata practice repo ~/src/myapp   Type your own Go code
+    kata practice keywords --rules stop-on-word,no-backspace
     kata --file lesson.txt   Practice with custom lesson file
     kata export json stats.json   Export to JSON
     kata export csv stats.csv     Export to CSV
+    kata --stats --lang rust --days 30 --group week
 
 "Slow is smooth. Smooth is fast."
 `
 	fmt.Print(help)
 }
 
-func printStats() {
+func printStats(filter stats.SessionFilter, groupBy stats.GroupBy) {
 	cfg, _ := config.Load()
 
 	db, err := stats.NewDB(cfg.DBPath)
@@ -178,17 +389,61 @@ func printStats() {
 	fmt.Println("📊 KATA Statistics")
 	fmt.Println()
 
-	avgWPM, err := db.GetAverageWPM()
-	if err == nil && avgWPM > 0 {
-		fmt.Printf("Average WPM: %.0f\n\n", avgWPM)
+	overall, err := db.AggregateSessions(filter, stats.GroupNone, nil)
+	if err == nil && len(overall) > 0 {
+		a := overall[0]
+		fmt.Printf("Sessions: %d (%d passed, %d failed) | Practice time: %s\n",
+			a.Count, a.Count-a.Failed, a.Failed, formatDuration(a.TotalDuration))
+		fmt.Printf("WPM: %.0f mean | %.0f median | %.0f p90 | %.0f best\n\n", a.MeanWPM, a.MedianWPM, a.P90WPM, a.BestWPM)
 	}
 
-	sessions, err := db.GetRecentSessions(5)
+	printHabits(db, cfg.Location())
+
+	if progress, err := progression.Load(db, cfg.Language); err == nil {
+		level := progress.Level
+		fmt.Printf("Level %d/%d (%s): %s\n", level.Number, len(progression.Levels), cfg.Language, level.Name)
+		if next, ok := progress.Next(); ok {
+			fmt.Printf("  Next: %s (%s)\n", next.Name, progress.Status())
+		}
+		fmt.Println()
+	}
+
+	if keys, err := db.GetUnlockedKeys(cfg.Layout, cfg.Language); err == nil && len(keys) > 0 {
+		fmt.Printf("Unlocked keys (%s): %s\n\n", cfg.Layout, strings.Join(keys, ""))
+	}
+
+	groups, err := db.AggregateSessions(filter, groupBy, nil)
+	if err == nil && len(groups) > 0 {
+		fmt.Println("Summary:")
+		for _, a := range groups {
+			fmt.Printf("  %-24s %4d sessions (%d failed) | WPM: %.0f (median %.0f, best %.0f) | Accuracy: %.1f%% | %s\n",
+				a.Group, a.Count, a.Failed, a.MeanWPM, a.MedianWPM, a.BestWPM, a.MeanAccuracy, formatDuration(a.TotalDuration))
+		}
+		fmt.Println()
+	}
+
+	records, err := db.GetPersonalBests()
+	if err == nil && len(records) > 0 {
+		fmt.Println("Personal Bests:")
+		for _, r := range records {
+			fmt.Printf("  %-24s %-7s | WPM: %.0f (%s) | Accuracy: %.1f%%\n",
+				stats.ModeLabel(r.Mode, r.Language), r.Length, r.BestWPM, r.BestWPMAt.Format("Jan 02"), r.BestAccuracy)
+		}
+		fmt.Println()
+	}
+
+	recent := filter
+	recent.Limit = 5
+	sessions, err := db.QuerySessions(recent)
 	if err == nil && len(sessions) > 0 {
 		fmt.Println("Recent Sessions:")
 		for _, s := range sessions {
-			fmt.Printf("  %s | WPM: %.0f | Accuracy: %.1f%%\n",
-				s.Timestamp.Format("Jan 02 15:04"), s.WPM, s.Accuracy)
+			result := ""
+			if !s.Passed {
+				result = " | failed"
+			}
+			fmt.Printf("  %s | %-24s | WPM: %.0f | Accuracy: %.1f%%%s\n",
+				s.Timestamp.Format("Jan 02 15:04"), s.Label(), s.WPM, s.Accuracy, result)
 		}
 		fmt.Println()
 	}
@@ -210,24 +465,118 @@ func printStats() {
 			fmt.Printf("  '%s' → %.0f%% errors (%d/%d)\n", keyDisplay, errorRate, k.Errors, total)
 		}
 	}
+
+	slowKeys, err := db.GetSlowestKeys(5)
+	if err == nil && len(slowKeys) > 0 {
+		fmt.Println()
+		fmt.Println("Slowest Keys:")
+		for _, k := range slowKeys {
+			fmt.Printf("  %q → %.0fms mean, %.0fms p90 (%d samples)\n", k.Key, k.MeanMs, k.P90Ms, k.Samples)
+		}
+	}
+
+	weakBigrams, err := db.GetWeakestBigrams(5)
+	if err == nil && len(weakBigrams) > 0 {
+		fmt.Println()
+		fmt.Println("Weakest Bigrams:")
+		for _, k := range weakBigrams {
+			total := k.Errors + k.Successes
+			errorRate := float64(k.Errors) / float64(total) * 100.0
+			fmt.Printf("  %q → %.0f%% errors (%d/%d)\n", k.Key, errorRate, k.Errors, total)
+		}
+	}
+
+	confusions, err := db.GetTopConfusions(5)
+	if err == nil && len(confusions) > 0 {
+		fmt.Println()
+		fmt.Println("Top Confusions (expected → typed):")
+		for _, c := range confusions {
+			fmt.Printf("  %q → %q  %d times\n", c.Expected, c.Typed, c.Count)
+		}
+	}
+}
+
+func printHabits(db *stats.DB, loc *time.Location) {
+	now := time.Now()
+
+	streaks, err := db.GetStreaks(now, loc)
+	if err != nil {
+		return
+	}
+	fmt.Printf("🔥 Streak: %d days (longest %d)\n\n", streaks.Current, streaks.Longest)
+
+	days, err := db.GetDailyActivity(now.AddDate(0, 0, -26*7+1), now, loc)
+	if err != nil || len(days) == 0 {
+		return
+	}
+
+	fmt.Println("Last 7 Days:")
+	for _, d := range days[max(0, len(days)-7):] {
+		fmt.Printf("  %s  %s\n", d.Date.Format("Mon Jan 02"), formatDuration(d.Seconds))
+	}
+	fmt.Println()
+
+	fmt.Print(calendar.Render(days))
+	fmt.Println()
 }
 
-func runPracticeMode(mode string) {
+func formatDuration(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+func printLanguages(current string) {
+	for _, lang := range generator.Languages() {
+		p, _ := generator.LookupPack(lang)
+		marker := "  "
+		if string(lang) == current {
+			marker = "▶ "
+		}
+		source := "built-in"
+		if p.Source != "" {
+			source = p.Source
+		}
+		fmt.Printf("%s%-12s %s\n", marker, lang, source)
+	}
+}
+
+func runPracticeMode(mode string, rules *engine.Rules) {
 	gen := generator.New()
 	var targetText string
+	var lessonType generator.LessonType
 
 	cfg, _ := config.Load()
+	gen.SetLanguage(generator.Language(cfg.Language))
+	gen.TopWords = cfg.TopWords
 
 	switch mode {
 	case "bigrams", "b":
+		lessonType = generator.TypeBigrams
+		if cfg.Corpus != "" {
+			if text, err := gen.GenerateFromFile(cfg.Corpus); err == nil {
+				gen.SetCorpus(text)
+			}
+		}
+		if db, err := stats.NewDB(cfg.DBPath); err == nil {
+			weak, _ := db.WeakBigramWeights(10)
+			gen.SetWeakBigrams(weak, cfg.BigramBias(generator.DefaultWeakBias))
+			db.Close()
+		}
 		targetText = strings.TrimSpace(gen.GenerateLesson(generator.TypeBigrams, 20))
 	case "keywords", "k":
+		lessonType = generator.TypeWords
 		targetText = strings.TrimSpace(gen.GenerateLesson(generator.TypeWords, 15))
 	case "symbols", "s":
+		lessonType = generator.TypeSymbols
 		targetText = strings.TrimSpace(gen.GenerateLesson(generator.TypeSymbols, 10))
 	case "code", "c":
+		lessonType = generator.TypeCode
 		targetText = strings.TrimSpace(gen.GenerateLesson(generator.TypeCode, 2))
 	case "weaknesses", "w":
+		lessonType = generator.TypeWeaknesses
 		db, err := stats.NewDB(cfg.DBPath)
 		if err != nil {
 			fmt.Printf("Error opening database: %v\n", err)
@@ -258,22 +607,76 @@ func runPracticeMode(mode string) {
 					ErrorRate: errorRate,
 				})
 			}
+			dueBigrams, err := db.GetDueBigrams(5)
+			if err != nil || len(dueBigrams) == 0 {
+				dueBigrams, _ = db.GetWeakestBigrams(5)
+			}
+			for _, k := range dueBigrams {
+				total := float64(k.Errors + k.Successes)
+				if total == 0 {
+					continue
+				}
+				weakList = append(weakList, generator.WeakKey{
+					Key:       k.Key,
+					ErrorRate: float64(k.Errors) / total,
+				})
+			}
+			confusions, _ := db.GetTopConfusions(3)
+			for _, c := range confusions {
+				weakList = append(weakList, generator.WeakKey{
+					Key:          c.Expected,
+					ErrorRate:    c.Rate,
+					ConfusedWith: c.Typed,
+				})
+			}
 			targetText = strings.TrimSpace(gen.GenerateWeaknessLesson(weakList, 20))
 		}
 	default:
 		fmt.Printf("Unknown practice mode: %s\n", mode)
-		fmt.Println("Available modes: bigrams, keywords, symbols, code, weaknesses")
+		fmt.Println("Available modes: continue, keys, bigrams, keywords, symbols, code, weaknesses, time, words, repo")
 		os.Exit(1)
 	}
 
-	p := tea.NewProgram(app.NewPractice(targetText))
+	p := tea.NewProgram(withRules(app.NewPractice(targetText, lessonType, ""), rules))
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v", err)
 		os.Exit(1)
 	}
 }
 
-func handleExport(format, output string) {
+// withRules applies the --rules flag, if given, to a practice model.
+func withRules(m tea.Model, rules *engine.Rules) tea.Model {
+	if rules == nil {
+		return m
+	}
+	return app.WithRules(m, *rules)
+}
+
+// runTest starts a timed ("time") or word-count ("words") test; arg is the
+// number of seconds or words.
+func runTest(kind, arg string, rules *engine.Rules) {
+	test := engine.TestMode{Kind: engine.TestTimed, Duration: 30 * time.Second}
+	if kind == "words" {
+		test = engine.TestMode{Kind: engine.TestWords, Words: 25}
+	}
+
+	if arg != "" {
+		var err error
+		test, err = engine.ParseTestMode(kind + ":" + arg)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	p := tea.NewProgram(withRules(app.NewTest(test), rules))
+	if _, err := p.Run(); err != nil {
+		fmt.Printf("Error: %v", err)
+		os.Exit(1)
+	}
+}
+
+func handleExport(format, output string, filter stats.SessionFilter) {
 	cfg, _ := config.Load()
 
 	db, err := stats.NewDB(cfg.DBPath)
@@ -289,13 +692,13 @@ func handleExport(format, output string) {
 
 	switch format {
 	case "json":
-		if err := export.ToJSON(db, output); err != nil {
+		if err := export.ToJSON(db, output, filter); err != nil {
 			fmt.Printf("Error exporting to JSON: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Printf("✓ Statistics exported to: %s\n", output)
 	case "csv":
-		if err := export.ToCSV(db, output); err != nil {
+		if err := export.ToCSV(db, output, filter); err != nil {
 			fmt.Printf("Error exporting to CSV: %v\n", err)
 			os.Exit(1)
 		}
@@ -307,21 +710,50 @@ func handleExport(format, output string) {
 	}
 }
 
-func runPracticeFromFile(filepath string) {
-	gen := generator.New()
-	content, err := gen.GenerateFromFile(filepath)
+// runPracticeRepo practises a snippet of the Go code under dir, favouring
+// snippets with the user's weakest keys.
+func runPracticeRepo(dir string, rules *engine.Rules) {
+	if dir == "" {
+		dir = "."
+	}
+	snippets, err := extract.Repo(dir, extract.DefaultOptions)
 	if err != nil {
-		fmt.Printf("Error reading file: %v\n", err)
+		fmt.Printf("Error reading %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+	if len(snippets) == 0 {
+		fmt.Printf("No Go snippets found in %s\n", dir)
+		os.Exit(1)
+	}
+
+	var weakKeys []string
+	cfg, _ := config.Load()
+	if db, err := stats.NewDB(cfg.DBPath); err == nil {
+		weak, _ := db.GetWeakestKeys(10)
+		for _, k := range weak {
+			weakKeys = append(weakKeys, k.Key)
+		}
+		db.Close()
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	snippet := extract.Pick(snippets, weakKeys, 1, rng)[0]
+
+	p := tea.NewProgram(withRules(app.NewPractice(snippet.Text, generator.TypeRepo, snippet.Source()), rules))
+	if _, err := p.Run(); err != nil {
+		fmt.Printf("Error: %v", err)
 		os.Exit(1)
 	}
+}
 
-	targetText := strings.TrimSpace(content)
-	if len(targetText) == 0 {
-		fmt.Println("Error: File is empty")
+func runPracticeFromFile(filepath string, rules *engine.Rules) {
+	m, err := app.NewFile(filepath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	p := tea.NewProgram(app.NewPractice(targetText))
+	p := tea.NewProgram(withRules(m, rules))
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v", err)
 		os.Exit(1)
@@ -0,0 +1,219 @@
+// Package align lines typed input up against the target text, so one
+// skipped or doubled character is reported as a single mistake instead of
+// shifting every following character out of place.
+package align
+
+import "math"
+
+// Op classifies one step of an alignment.
+type Op int8
+
+const (
+	Match Op = iota
+	Substitution
+	Insertion     // an extra rune was typed
+	Omission      // a target rune was skipped
+	Transposition // two adjacent target runes were typed in swapped order
+)
+
+func (o Op) String() string {
+	switch o {
+	case Match:
+		return "match"
+	case Substitution:
+		return "substitution"
+	case Insertion:
+		return "insertion"
+	case Omission:
+		return "omission"
+	case Transposition:
+		return "transposition"
+	}
+	return "unknown"
+}
+
+// Edit is one step of an alignment. TargetPos and InputPos are where the
+// step starts; Expected is 0 for insertions and Typed is 0 for omissions.
+// A transposition covers two runes on both sides, starting at the given
+// positions.
+type Edit struct {
+	Op        Op
+	TargetPos int
+	InputPos  int
+	Expected  rune
+	Typed     rune
+}
+
+// Counts tallies the mistakes in an alignment by class.
+type Counts struct {
+	Substitutions  int
+	Insertions     int
+	Omissions      int
+	Transpositions int
+}
+
+func (c Counts) Total() int {
+	return c.Substitutions + c.Insertions + c.Omissions + c.Transpositions
+}
+
+// Band is how far, in runes, the input may drift from the target before
+// the alignment stops looking for a resynchronisation.
+const Band = 16
+
+// Align computes a minimum-cost alignment (optimal string alignment
+// distance: substitutions, insertions, omissions and adjacent
+// transpositions all cost 1) of input against the prefix of target that it
+// attempts. The untyped rest of the target is not part of the result.
+// Among equally cheap alignments the one covering more of the target wins,
+// so a short input that ends early reads as an omission rather than a
+// substitution chain.
+func Align(target, input []rune) []Edit {
+	n := len(input)
+	if len(target) > n+Band {
+		target = target[:n+Band]
+	}
+	m := len(target)
+
+	band := Band
+	if n-m > band {
+		band = n - m
+	}
+	width := 2*band + 1
+
+	const inf = math.MaxInt32
+	cost := make([]int32, (m+1)*width)
+	step := make([]Op, (m+1)*width)
+	for i := range cost {
+		cost[i] = inf
+	}
+
+	// cell maps (i, j) into the band, or -1 outside it.
+	cell := func(i, j int) int {
+		k := j - i + band
+		if i < 0 || j < 0 || j > n || k < 0 || k >= width {
+			return -1
+		}
+		return i*width + k
+	}
+	at := func(i, j int) int32 {
+		if c := cell(i, j); c >= 0 {
+			return cost[c]
+		}
+		return inf
+	}
+
+	cost[cell(0, 0)] = 0
+	for i := 0; i <= m; i++ {
+		for j := max(0, i-band); j <= min(n, i+band); j++ {
+			if i == 0 && j == 0 {
+				continue
+			}
+			best, op := int32(inf), Match
+
+			if i > 0 && j > 0 {
+				if c := at(i-1, j-1); c < inf {
+					if target[i-1] == input[j-1] {
+						best, op = c, Match
+					} else {
+						best, op = c+1, Substitution
+					}
+				}
+			}
+			if i > 1 && j > 1 && target[i-1] == input[j-2] && target[i-2] == input[j-1] && target[i-1] != target[i-2] {
+				if c := at(i-2, j-2); c < inf && c+1 < best {
+					best, op = c+1, Transposition
+				}
+			}
+			if i > 0 {
+				if c := at(i-1, j); c < inf && c+1 < best {
+					best, op = c+1, Omission
+				}
+			}
+			if j > 0 {
+				if c := at(i, j-1); c < inf && c+1 < best {
+					best, op = c+1, Insertion
+				}
+			}
+
+			c := cell(i, j)
+			cost[c], step[c] = best, op
+		}
+	}
+
+	end := -1
+	for i := max(0, n-band); i <= min(m, n+band); i++ {
+		if end < 0 || at(i, n) <= at(end, n) {
+			end = i
+		}
+	}
+
+	var edits []Edit
+	for i, j := end, n; i > 0 || j > 0; {
+		op := step[cell(i, j)]
+		switch op {
+		case Match, Substitution:
+			i, j = i-1, j-1
+			edits = append(edits, Edit{Op: op, TargetPos: i, InputPos: j, Expected: target[i], Typed: input[j]})
+		case Transposition:
+			i, j = i-2, j-2
+			edits = append(edits, Edit{Op: op, TargetPos: i, InputPos: j, Expected: target[i], Typed: input[j]})
+		case Omission:
+			i--
+			edits = append(edits, Edit{Op: op, TargetPos: i, InputPos: j, Expected: target[i]})
+		case Insertion:
+			j--
+			edits = append(edits, Edit{Op: op, TargetPos: i, InputPos: j, Typed: input[j]})
+		}
+	}
+
+	for l, r := 0, len(edits)-1; l < r; l, r = l+1, r-1 {
+		edits[l], edits[r] = edits[r], edits[l]
+	}
+	return edits
+}
+
+// Summarize counts the mistakes in an alignment.
+func Summarize(edits []Edit) Counts {
+	var c Counts
+	for _, e := range edits {
+		switch e.Op {
+		case Substitution:
+			c.Substitutions++
+		case Insertion:
+			c.Insertions++
+		case Omission:
+			c.Omissions++
+		case Transposition:
+			c.Transpositions++
+		}
+	}
+	return c
+}
+
+// Outcome is how one rune of the target was typed.
+type Outcome int8
+
+const (
+	Untyped Outcome = iota
+	Correct
+	Wrong
+)
+
+// Outcomes returns, for every rune of a target of length n, whether the
+// alignment has it typed correctly, wrongly (substituted, omitted or
+// transposed) or not yet. Insertions belong to no target rune.
+func Outcomes(edits []Edit, n int) []Outcome {
+	out := make([]Outcome, n)
+	for _, e := range edits {
+		switch e.Op {
+		case Match:
+			out[e.TargetPos] = Correct
+		case Substitution, Omission:
+			out[e.TargetPos] = Wrong
+		case Transposition:
+			out[e.TargetPos] = Wrong
+			out[e.TargetPos+1] = Wrong
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package align
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAlign(t *testing.T) {
+	cases := []struct {
+		name   string
+		target string
+		input  string
+		counts Counts
+	}{
+		{"perfect", "hello world", "hello world", Counts{}},
+		{"prefix", "hello world", "hello", Counts{}},
+		{"empty input", "hello", "", Counts{}},
+		{"substitution", "hello", "hallo", Counts{Substitutions: 1}},
+		{"trailing substitution", "hello", "hella", Counts{Substitutions: 1}},
+		{"omission", "hello world", "helo world", Counts{Omissions: 1}},
+		{"insertion", "hello world", "helllo world", Counts{Insertions: 1}},
+		{"transposition", "the cat", "teh cat", Counts{Transpositions: 1}},
+		{"typed past the end", "abc", "abcxx", Counts{Insertions: 2}},
+		{"omission mid-word", "func main() {", "fun main() {", Counts{Omissions: 1}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			edits := Align([]rune(tc.target), []rune(tc.input))
+			if got := Summarize(edits); got != tc.counts {
+				t.Errorf("Expected %+v, got %+v", tc.counts, got)
+			}
+		})
+	}
+}
+
+func TestAlignEdits(t *testing.T) {
+	edits := Align([]rune("abcd"), []rune("acbd"))
+	want := []Edit{
+		{Op: Match, TargetPos: 0, InputPos: 0, Expected: 'a', Typed: 'a'},
+		{Op: Transposition, TargetPos: 1, InputPos: 1, Expected: 'b', Typed: 'c'},
+		{Op: Match, TargetPos: 3, InputPos: 3, Expected: 'd', Typed: 'd'},
+	}
+	if len(edits) != len(want) {
+		t.Fatalf("Expected %d edits, got %+v", len(want), edits)
+	}
+	for i := range want {
+		if edits[i] != want[i] {
+			t.Errorf("Edit %d: expected %+v, got %+v", i, want[i], edits[i])
+		}
+	}
+}
+
+func TestOutcomes(t *testing.T) {
+	target := []rune("hello world")
+	edits := Align(target, []rune("hllo w"))
+	got := Outcomes(edits, len(target))
+
+	want := []Outcome{Correct, Wrong, Correct, Correct, Correct, Correct, Correct, Untyped, Untyped, Untyped, Untyped}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Position %d (%q): expected %d, got %d", i, target[i], want[i], got[i])
+		}
+	}
+}
+
+func TestAlignLongText(t *testing.T) {
+	target := strings.Repeat("if err != nil { return err }\n", 500)
+	input := strings.Replace(target, "return", "retrn", 1)
+
+	counts := Summarize(Align([]rune(target), []rune(input)))
+	if counts.Total() != 1 || counts.Omissions != 1 {
+		t.Errorf("Expected a single omission in a long text, got %+v", counts)
+	}
+}
@@ -0,0 +1,99 @@
+package calendar
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"kata/pkg/stats"
+)
+
+var levelColors = []lipgloss.Color{
+	lipgloss.Color("240"),     // Gray - no practice
+	lipgloss.Color("#40613d"), // < 5 minutes
+	lipgloss.Color("#5f9e5a"), // < 15 minutes
+	lipgloss.Color("#8fd18a"), // < 30 minutes
+	lipgloss.Color("#a6e3a1"), // 30 minutes or more
+}
+
+var weekdayLabels = []string{"Mon", "   ", "Wed", "   ", "Fri", "   ", "Sun"}
+
+func GetLevel(seconds float64) int {
+	minutes := seconds / 60
+	switch {
+	case seconds <= 0:
+		return 0
+	case minutes < 5:
+		return 1
+	case minutes < 15:
+		return 2
+	case minutes < 30:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// weekdayIndex maps time.Weekday to a Monday-first row.
+func weekdayIndex(d time.Time) int {
+	return (int(d.Weekday()) + 6) % 7
+}
+
+// Render draws a GitHub-style practice calendar with one column per week
+// and one row per weekday. days must be consecutive and ascending, as
+// returned by stats.DB.GetDailyActivity.
+func Render(days []stats.DayActivity) string {
+	if len(days) == 0 {
+		return ""
+	}
+
+	offset := weekdayIndex(days[0].Date)
+	weeks := (offset + len(days) + 6) / 7
+
+	grid := make([][]string, 7)
+	for row := range grid {
+		grid[row] = make([]string, weeks)
+		for col := range grid[row] {
+			grid[row][col] = "  "
+		}
+	}
+
+	monthLine := []rune(strings.Repeat(" ", weeks*2))
+	lastMonth := time.Month(0)
+
+	for i, day := range days {
+		cell := offset + i
+		row, col := cell%7, cell/7
+
+		style := lipgloss.NewStyle().Foreground(levelColors[GetLevel(day.Seconds)])
+		grid[row][col] = style.Render("■") + " "
+
+		if day.Date.Month() != lastMonth {
+			lastMonth = day.Date.Month()
+			label := []rune(day.Date.Format("Jan"))
+			if col*2+len(label) <= len(monthLine) && (col == 0 || monthLine[col*2-1] == ' ') {
+				copy(monthLine[col*2:], label)
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("    ")
+	b.WriteString(strings.TrimRight(string(monthLine), " "))
+	b.WriteString("\n")
+
+	for row, cells := range grid {
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(weekdayLabels[row]))
+		b.WriteString(" ")
+		b.WriteString(strings.Join(cells, ""))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("    Less ")
+	for _, color := range levelColors {
+		b.WriteString(lipgloss.NewStyle().Foreground(color).Render("■") + " ")
+	}
+	b.WriteString("More\n")
+
+	return b.String()
+}
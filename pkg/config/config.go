@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,8 +14,58 @@ type Config struct {
 	Language string `yaml:"language"`
 	ZenMode  bool   `yaml:"zen_mode"`
 	DBPath   string `yaml:"db_path"`
+	// Timezone is an IANA name used for day boundaries in streaks and the
+	// practice calendar. Empty means the system's local time zone.
+	Timezone string `yaml:"timezone,omitempty"`
+	// Scheduler selects the spaced-repetition algorithm: "sm2" (default)
+	// or "fsrs".
+	Scheduler string `yaml:"scheduler,omitempty"`
+	// Rules are the default strictness rules, comma-separated: any of
+	// stop-on-letter, stop-on-word, no-backspace and sudden-death.
+	Rules string `yaml:"rules,omitempty"`
+	// AccuracyThreshold is the accuracy, in percent, a session needs to
+	// pass. Failed sessions are offered again and set no records. Unset
+	// means the default; 0 turns the gate off.
+	AccuracyThreshold *float64 `yaml:"accuracy_threshold,omitempty"`
+	// Layout is the keyboard layout key-unlock lessons start from:
+	// qwerty (default), qwertz, azerty, dvorak or colemak.
+	Layout string `yaml:"layout,omitempty"`
+	// Corpus is a text file bigram lessons learn their pseudo-words from,
+	// instead of the built-in word lists.
+	Corpus string `yaml:"corpus,omitempty"`
+	// WeakBigramBias is how strongly pseudo-words favour the user's weak
+	// bigrams. Unset means the default; 0 turns the bias off.
+	WeakBigramBias *float64 `yaml:"weak_bigram_bias,omitempty"`
+	// TopWords limits word lessons to the N most common words, for
+	// beginners. 0 uses every word.
+	TopWords int `yaml:"top_words,omitempty"`
+	// AutoIndent types the leading indentation of code lines after Enter,
+	// as an editor would; TrimTrailing lets Enter skip whitespace left at
+	// the end of a line. Neither counts towards key stats or WPM.
+	AutoIndent   bool `yaml:"auto_indent,omitempty"`
+	TrimTrailing bool `yaml:"trim_trailing,omitempty"`
 }
 
+// BigramBias returns the configured weak-bigram bias, or def if unset.
+func (c Config) BigramBias(def float64) float64 {
+	if c.WeakBigramBias == nil {
+		return def
+	}
+	return *c.WeakBigramBias
+}
+
+// AccuracyGate returns the configured accuracy threshold, or the default
+// if unset.
+func (c Config) AccuracyGate() float64 {
+	if c.AccuracyThreshold == nil {
+		return DefaultAccuracyThreshold
+	}
+	return *c.AccuracyThreshold
+}
+
+// DefaultAccuracyThreshold is the "Accuracy First" gate.
+const DefaultAccuracyThreshold = 95
+
 func GetDataDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -23,6 +74,16 @@ func GetDataDir() (string, error) {
 	return filepath.Join(homeDir, ".kata"), nil
 }
 
+// GetLanguagesDir is where language packs are loaded from, next to the
+// built-in languages.
+func GetLanguagesDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "languages"), nil
+}
+
 func DefaultConfig() Config {
 	dataDir, _ := GetDataDir()
 	// Fallback to local if home dir fails
@@ -36,6 +97,7 @@ func DefaultConfig() Config {
 		Language: "go",
 		ZenMode:  false,
 		DBPath:   dbPath,
+		Layout:   "qwerty",
 	}
 }
 
@@ -84,6 +146,9 @@ func Load() (Config, error) {
 		def := DefaultConfig()
 		cfg.DBPath = def.DBPath
 	}
+	if cfg.Layout == "" {
+		cfg.Layout = "qwerty"
+	}
 
 	return cfg, nil
 }
@@ -120,3 +185,16 @@ func (c *Config) SetZenMode(enabled bool) error {
 	c.ZenMode = enabled
 	return Save(*c)
 }
+
+// Location returns the configured time zone, falling back to local time
+// when it is unset or unknown.
+func (c Config) Location() *time.Location {
+	if c.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
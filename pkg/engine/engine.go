@@ -4,6 +4,8 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"kata/pkg/align"
 )
 
 type Engine struct {
@@ -13,6 +15,34 @@ type Engine struct {
 	EndTime    time.Time
 	IsFinished bool
 	ErrorCount int
+	// Errors classifies ErrorCount by kind of mistake.
+	Errors align.Counts
+
+	// Test is the session's shape; the zero value is a plain lesson.
+	Test TestMode
+	// Rules are the strictness settings; Failed is set when sudden death
+	// ends the session early.
+	Rules  Rules
+	Failed bool
+
+	// Indent is the whitespace the engine types for the user; filled
+	// maps the input positions it typed to the target positions they fill.
+	Indent Indentation
+	filled map[int]int
+
+	// Keystrokes is the ordered event log of everything typed and deleted.
+	Keystrokes []Keystroke
+	furthest   int
+
+	// The alignment of the input against the target. Its first settled
+	// edits, which align input[:syncInput] with target[:syncTarget], lie
+	// far enough behind the cursor not to change again, so only the rest
+	// is realigned as the user types.
+	edits      []align.Edit
+	settled    int
+	settledErr align.Counts
+	syncTarget int
+	syncInput  int
 }
 
 func New(targetText string) *Engine {
@@ -28,36 +58,91 @@ func (e *Engine) ProcessKey(msg tea.KeyMsg) {
 		return
 	}
 
+	now := time.Now()
 	if e.StartTime.IsZero() {
-		e.StartTime = time.Now()
+		e.StartTime = now
+	}
+	// A key pressed after a timed test ran out ends it instead of counting.
+	if e.Tick(now); e.IsFinished {
+		return
 	}
 
+	before := e.UserInput
 	oldLength := len(e.UserInput)
+	logged := len(e.Keystrokes)
 
+	var typed []rune
 	switch msg.String() {
-	case "backspace":
-		if len(e.UserInput) > 0 {
-			e.UserInput = e.UserInput[:len(e.UserInput)-1]
+	case "backspace", "ctrl+backspace", "ctrl+h", "ctrl+w":
+		if e.Rules.NoBackspace {
+			break
+		}
+		n := len(e.UserInput)
+		if msg.String() == "backspace" {
+			n = max(n-1, 0)
+		} else {
+			n = len(e.deleteLastWord(e.UserInput))
+		}
+		if n < oldLength {
+			n = e.unfill(n)
 		}
-	case "ctrl+backspace", "ctrl+h", "ctrl+w":
-		e.UserInput = e.deleteLastWord(e.UserInput)
+		e.recordDeletes(before[n:oldLength], n, now)
+		e.truncate(n)
+		e.fillIndent()
 	case "enter":
-		e.UserInput = append(e.UserInput, '\n')
+		typed = []rune{'\n'}
 	case "tab":
-		e.UserInput = append(e.UserInput, '\t')
+		typed = []rune{'\t'}
 	default:
 		if len(msg.Runes) > 0 {
-			e.UserInput = append(e.UserInput, msg.Runes...)
+			typed = msg.Runes
 		} else if len(msg.String()) == 1 {
-			e.UserInput = append(e.UserInput, rune(msg.String()[0]))
+			typed = []rune{rune(msg.String()[0])}
 		}
 	}
 
-	e.updateErrorsIncremental(oldLength)
+	if len(typed) > 0 {
+		e.fillIndent()
+	}
+	for _, r := range typed {
+		start := len(e.UserInput)
+		if r == '\n' {
+			e.collapseTrailing()
+		}
+		expected := e.expectedAt(e.aligned())
+		if !e.accept(r) {
+			e.truncate(start)
+			e.recordRejected(r, expected, now)
+			continue
+		}
+		e.UserInput = append(e.UserInput, r)
+		e.recordInsert(expected, now)
+		e.fillIndent()
+	}
+
+	// Deleting a rune and filling indentation back in can leave the length
+	// unchanged, so realign regardless.
+	e.calculateErrors()
+	if e.Rules.SuddenDeath && e.mistakeSince(logged) {
+		e.fail(now)
+		return
+	}
 	e.checkCompletion()
 }
 
 func (e *Engine) checkCompletion() {
+	switch e.Test.Kind {
+	case TestTimed:
+		// Only the clock ends a timed test; see Tick.
+		return
+	case TestWords:
+		if len(e.UserInput) >= len(e.TargetText) {
+			e.IsFinished = true
+			e.EndTime = time.Now()
+		}
+		return
+	}
+
 	if len(e.UserInput) >= len(e.TargetText) {
 		match := true
 		for i := 0; i < len(e.TargetText); i++ {
@@ -74,42 +159,63 @@ func (e *Engine) checkCompletion() {
 	}
 }
 
+// calculateErrors aligns the input against the target, so a skipped or
+// doubled rune is one error rather than a shift of everything after it.
 func (e *Engine) calculateErrors() {
-	e.ErrorCount = 0
-	minLength := min(len(e.UserInput), len(e.TargetText))
+	if len(e.UserInput) < e.syncInput {
+		e.edits, e.settled, e.settledErr = nil, 0, align.Counts{}
+		e.syncTarget, e.syncInput = 0, 0
+	}
 
-	for i := 0; i < minLength; i++ {
-		if e.UserInput[i] != e.TargetText[i] {
-			e.ErrorCount++
-		}
+	e.edits = e.edits[:e.settled]
+	for _, ed := range align.Align(e.TargetText[e.syncTarget:], e.UserInput[e.syncInput:]) {
+		ed.TargetPos += e.syncTarget
+		ed.InputPos += e.syncInput
+		e.edits = append(e.edits, ed)
 	}
 
-	if len(e.UserInput) > len(e.TargetText) {
-		e.ErrorCount += len(e.UserInput) - len(e.TargetText)
+	// Settle on the last match more than two bands behind the cursor.
+	limit := len(e.UserInput) - 2*align.Band
+	for i := e.settled; i < len(e.edits) && e.edits[i].InputPos < limit; i++ {
+		if ed := e.edits[i]; ed.Op == align.Match {
+			e.settledErr = addCounts(e.settledErr, align.Summarize(e.edits[e.settled:i+1]))
+			e.settled, e.syncTarget, e.syncInput = i+1, ed.TargetPos+1, ed.InputPos+1
+		}
 	}
-}
 
-func (e *Engine) updateErrorsIncremental(oldLength int) {
-	newLength := len(e.UserInput)
+	e.Errors = addCounts(e.settledErr, align.Summarize(e.edits[e.settled:]))
+	e.ErrorCount = e.Errors.Total()
+}
 
-	if newLength < oldLength {
-		e.calculateErrors()
-		return
+func addCounts(a, b align.Counts) align.Counts {
+	return align.Counts{
+		Substitutions:  a.Substitutions + b.Substitutions,
+		Insertions:     a.Insertions + b.Insertions,
+		Omissions:      a.Omissions + b.Omissions,
+		Transpositions: a.Transpositions + b.Transpositions,
 	}
+}
 
-	if newLength == oldLength {
-		return
-	}
+// Edits returns the alignment of the input against the target that
+// ErrorCount is counted from.
+func (e *Engine) Edits() []align.Edit {
+	return e.edits
+}
 
-	for i := oldLength; i < newLength; i++ {
-		if i < len(e.TargetText) {
-			if e.UserInput[i] != e.TargetText[i] {
-				e.ErrorCount++
-			}
-		} else {
-			e.ErrorCount++
-		}
+// Cursor is the position in the target the next rune typed is aligned
+// with.
+func (e *Engine) Cursor() int {
+	if len(e.edits) == 0 {
+		return 0
 	}
+	last := e.edits[len(e.edits)-1]
+	switch last.Op {
+	case align.Insertion:
+		return last.TargetPos
+	case align.Transposition:
+		return last.TargetPos + 2
+	}
+	return last.TargetPos + 1
 }
 
 func (e *Engine) deleteLastWord(input []rune) []rune {
@@ -160,13 +266,21 @@ func (e *Engine) GetStats() (wpm float64, accuracy float64, duration float64) {
 
 	// Calculate WPM based on correct characters typed, not total target length
 	// Standard: 1 word = 5 characters
-	correctChars := max(0, len(e.TargetText)-e.ErrorCount)
+	// Tests that can end before the text does only count what was reached.
+	reached := len(e.TargetText)
+	if e.Test.Kind != TestText || e.Failed {
+		reached = min(len(e.UserInput), len(e.TargetText))
+	}
+	correctChars := max(0, reached-e.ErrorCount-e.filledBefore(reached))
 	words := float64(correctChars) / 5.0
 	wpm = (words / duration) * 60.0
 
-	// Calculate accuracy based on actual attempts made
-	totalAttempts := len(e.UserInput)
-	if totalAttempts == 0 {
+	// Calculate accuracy based on actual attempts made, including
+	// mistakes that were corrected since
+	totalAttempts := len(e.UserInput) - len(e.filled)
+	if keyAccuracy, ok := e.typingAccuracy(); ok {
+		accuracy = keyAccuracy
+	} else if totalAttempts == 0 {
 		accuracy = 100.0
 	} else {
 		correctAttempts := max(0, totalAttempts-e.ErrorCount)
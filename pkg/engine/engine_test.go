@@ -1,10 +1,13 @@
 package engine
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"kata/pkg/align"
 )
 
 func TestEngineTyping(t *testing.T) {
@@ -155,3 +158,468 @@ func TestAccuracyCalculation(t *testing.T) {
 		})
 	}
 }
+
+func TestKeystrokeLog(t *testing.T) {
+	e := New("abc")
+
+	for _, char := range "ax" {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{char}})
+	}
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	for _, char := range "bc" {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{char}})
+	}
+
+	expected := []Keystroke{
+		{Position: 0, Expected: 'a', Typed: 'a', Kind: KeystrokeInsert},
+		{Position: 1, Expected: 'b', Typed: 'x', Kind: KeystrokeInsert},
+		{Position: 1, Expected: 'b', Typed: 'x', Kind: KeystrokeDelete},
+		{Position: 1, Expected: 'b', Typed: 'b', Kind: KeystrokeCorrection},
+		{Position: 2, Expected: 'c', Typed: 'c', Kind: KeystrokeInsert},
+	}
+
+	if len(e.Keystrokes) != len(expected) {
+		t.Fatalf("Expected %d keystrokes, got %d", len(expected), len(e.Keystrokes))
+	}
+
+	for i, want := range expected {
+		got := e.Keystrokes[i]
+		if got.Position != want.Position || got.Expected != want.Expected ||
+			got.Typed != want.Typed || got.Kind != want.Kind {
+			t.Errorf("Keystroke %d: expected %+v, got %+v", i, want, got)
+		}
+		if got.Time.IsZero() {
+			t.Errorf("Keystroke %d has no timestamp", i)
+		}
+	}
+}
+
+func TestKeystrokeLogAfterSkip(t *testing.T) {
+	e := New("hello world")
+
+	// The second l is skipped; only the o typed in its place is wrong.
+	for _, char := range "helo wo" {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{char}})
+	}
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyBackspace})
+
+	expected := []Keystroke{
+		{Position: 0, Expected: 'h', Typed: 'h', Kind: KeystrokeInsert},
+		{Position: 1, Expected: 'e', Typed: 'e', Kind: KeystrokeInsert},
+		{Position: 2, Expected: 'l', Typed: 'l', Kind: KeystrokeInsert},
+		{Position: 3, Expected: 'l', Typed: 'o', Kind: KeystrokeInsert},
+		{Position: 4, Expected: ' ', Typed: ' ', Kind: KeystrokeInsert},
+		{Position: 5, Expected: 'w', Typed: 'w', Kind: KeystrokeInsert},
+		{Position: 6, Expected: 'o', Typed: 'o', Kind: KeystrokeInsert},
+		{Position: 6, Expected: 'o', Typed: 'o', Kind: KeystrokeDelete},
+	}
+
+	if len(e.Keystrokes) != len(expected) {
+		t.Fatalf("Expected %d keystrokes, got %d", len(expected), len(e.Keystrokes))
+	}
+	for i, want := range expected {
+		got := e.Keystrokes[i]
+		if got.Position != want.Position || got.Expected != want.Expected ||
+			got.Typed != want.Typed || got.Kind != want.Kind {
+			t.Errorf("Keystroke %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+	if e.ErrorCount != 1 {
+		t.Errorf("Expected 1 error, got %d", e.ErrorCount)
+	}
+}
+
+func TestKeystrokeLogWordDelete(t *testing.T) {
+	e := New("ab cd")
+
+	for _, char := range "ab cx" {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{char}})
+	}
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyCtrlW})
+
+	deletes := e.Keystrokes[5:]
+	if len(deletes) != 2 {
+		t.Fatalf("Expected 2 delete events, got %d", len(deletes))
+	}
+	if deletes[0].Typed != 'x' || deletes[0].Position != 4 {
+		t.Errorf("Expected 'x' at 4 to be deleted first, got %+v", deletes[0])
+	}
+	if deletes[1].Typed != 'c' || deletes[1].Position != 3 {
+		t.Errorf("Expected 'c' at 3 to be deleted second, got %+v", deletes[1])
+	}
+}
+
+func TestErrorsAreAligned(t *testing.T) {
+	e := New("hello world")
+
+	// Skipping the second "l" is one omission, not six shifted errors.
+	for _, char := range "helo wor" {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{char}})
+	}
+
+	if e.ErrorCount != 1 || e.Errors.Omissions != 1 {
+		t.Errorf("Expected a single omission, got %d errors (%+v)", e.ErrorCount, e.Errors)
+	}
+
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyCtrlW})
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyCtrlW})
+	if e.ErrorCount != 0 {
+		t.Errorf("Expected no errors after deleting the mistyped word, got %d", e.ErrorCount)
+	}
+}
+
+func TestIncrementalAlignment(t *testing.T) {
+	target := strings.Repeat("the quick brown fox jumps over the lazy dog ", 6)
+	// A skipped rune, a doubled one and a swap, far apart, then a few
+	// backspaces into the settled part.
+	typed := strings.Replace(target, "quick", "quck", 1)
+	typed = strings.Replace(typed, "lazy", "lazzy", 3)
+	typed = strings.Replace(typed, "fox", "fxo", 5)[:200]
+
+	e := New(target)
+	typeString(e, typed)
+	for i := 0; i < 40; i++ {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	typeString(e, typed[160:])
+
+	full := align.Align([]rune(target), []rune(typed))
+	if want := align.Summarize(full); e.Errors != want {
+		t.Errorf("Expected the errors of a full alignment, %+v, got %+v", want, e.Errors)
+	}
+	if got, want := e.Cursor(), full[len(full)-1].TargetPos+1; got != want {
+		t.Errorf("Expected the cursor at %d, got %d", want, got)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	e := New("abcd")
+
+	for _, char := range "ax" {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{char}})
+	}
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	for _, char := range "bcd" {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{char}})
+	}
+
+	// Spread the six key presses evenly over one minute.
+	start := time.Now().Add(-time.Minute)
+	for i := range e.Keystrokes {
+		e.Keystrokes[i].Time = start.Add(time.Duration(i) * time.Second)
+	}
+	e.StartTime = start
+	e.EndTime = start.Add(time.Minute)
+
+	m := e.Metrics()
+	if m.CorrectedErrors != 1 || m.UncorrectedErrors != 0 {
+		t.Errorf("Expected 1 corrected and 0 uncorrected errors, got %d and %d", m.CorrectedErrors, m.UncorrectedErrors)
+	}
+	// Five runes typed (a, x, b, c, d) in a minute.
+	if m.RawWPM < 0.99 || m.RawWPM > 1.01 {
+		t.Errorf("Expected raw WPM 1, got %.2f", m.RawWPM)
+	}
+	if m.NetWPM < 0.79 || m.NetWPM > 0.81 {
+		t.Errorf("Expected net WPM 0.8, got %.2f", m.NetWPM)
+	}
+	if m.Accuracy != 80 {
+		t.Errorf("Expected the corrected mistake to cost accuracy (80%%), got %.1f%%", m.Accuracy)
+	}
+	if m.Consistency != 100 {
+		t.Errorf("Expected perfect consistency for an even rhythm, got %.1f", m.Consistency)
+	}
+
+	e.Keystrokes[3].Time = e.Keystrokes[2].Time.Add(1500 * time.Millisecond)
+	if c := e.Metrics().Consistency; c >= 100 || c <= 0 {
+		t.Errorf("Expected an uneven rhythm to lower consistency, got %.1f", c)
+	}
+}
+
+func TestTimedTest(t *testing.T) {
+	e := NewTest("one two", TestMode{Kind: TestTimed, Duration: 30 * time.Second})
+
+	if e.Remaining(time.Now()) != 30*time.Second {
+		t.Errorf("Expected the clock to wait for the first key, got %v", e.Remaining(time.Now()))
+	}
+	if !e.NeedsMoreText() {
+		t.Error("Expected a short timed text to ask for more")
+	}
+	e.AppendText("three four")
+	if string(e.TargetText) != "one two three four" {
+		t.Errorf("Unexpected text after append: %q", string(e.TargetText))
+	}
+
+	for _, char := range "one twx" {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{char}})
+	}
+	start := e.StartTime
+
+	e.Tick(start.Add(10 * time.Second))
+	if e.IsFinished {
+		t.Fatal("Timed test finished early")
+	}
+	if r := e.Remaining(start.Add(10 * time.Second)); r != 20*time.Second {
+		t.Errorf("Expected 20s remaining, got %v", r)
+	}
+
+	e.Tick(start.Add(31 * time.Second))
+	if !e.IsFinished || !e.EndTime.Equal(start.Add(30*time.Second)) {
+		t.Fatalf("Expected the test to end at exactly 30s, got finished=%v end=%v", e.IsFinished, e.EndTime)
+	}
+
+	if got := e.Typed(); got != "one two" {
+		t.Errorf("Expected only the typed text to be kept, got %q", got)
+	}
+
+	// Seven runes reached, one wrong, over 30 seconds.
+	wpm, _, _ := e.GetStats()
+	if wpm < 2.39 || wpm > 2.41 {
+		t.Errorf("Expected 2.4 WPM from the reached text only, got %.2f", wpm)
+	}
+	if e.WordsTyped() != 1 {
+		t.Errorf("Expected 1 word typed, got %d", e.WordsTyped())
+	}
+}
+
+func TestTimedTestDeadlineBetweenTicks(t *testing.T) {
+	e := NewTest("one two", TestMode{Kind: TestTimed, Duration: 30 * time.Second})
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}})
+
+	// The deadline passes before the next tick arrives.
+	e.StartTime = e.StartTime.Add(-31 * time.Second)
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+
+	if !e.IsFinished {
+		t.Fatal("Expected a key after the deadline to end the test")
+	}
+	if string(e.UserInput) != "o" || len(e.Keystrokes) != 1 {
+		t.Errorf("Expected the late key to be ignored, got %q and %d keystrokes", string(e.UserInput), len(e.Keystrokes))
+	}
+	if !e.EndTime.Equal(e.StartTime.Add(30 * time.Second)) {
+		t.Errorf("Expected the test to end at the deadline, got %v", e.EndTime.Sub(e.StartTime))
+	}
+}
+
+func TestWordsTest(t *testing.T) {
+	e := NewTest("ab cd", TestMode{Kind: TestWords, Words: 2})
+
+	for _, char := range "ab cx" {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{char}})
+	}
+
+	if !e.IsFinished {
+		t.Fatal("Expected a word test to end on the last word, even with a mistake")
+	}
+	if e.ErrorCount != 1 || e.WordsTyped() != 2 {
+		t.Errorf("Expected 1 error over 2 words, got %d over %d", e.ErrorCount, e.WordsTyped())
+	}
+}
+
+func TestParseTestMode(t *testing.T) {
+	for _, mode := range []TestMode{
+		{},
+		{Kind: TestTimed, Duration: 60 * time.Second},
+		{Kind: TestWords, Words: 25},
+	} {
+		parsed, err := ParseTestMode(mode.String())
+		if err != nil || parsed != mode {
+			t.Errorf("Round trip of %q failed: %+v, %v", mode.String(), parsed, err)
+		}
+	}
+
+	for _, bad := range []string{"time", "time:0", "words:x", "laps:3"} {
+		if _, err := ParseTestMode(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func typeString(e *Engine, s string) {
+	for _, char := range s {
+		e.ProcessKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{char}})
+	}
+}
+
+func TestStopOnLetter(t *testing.T) {
+	e := New("abc")
+	e.Rules = Rules{StopOnLetter: true}
+
+	typeString(e, "axbc")
+
+	if string(e.UserInput) != "abc" || !e.IsFinished {
+		t.Fatalf("Expected the wrong key to be held back, got %q", string(e.UserInput))
+	}
+	if e.Keystrokes[1].Kind != KeystrokeRejected || e.Keystrokes[1].Typed != 'x' {
+		t.Errorf("Expected a rejected 'x', got %+v", e.Keystrokes[1])
+	}
+	if m := e.Metrics(); m.CorrectedErrors != 1 || m.Accuracy != 75 {
+		t.Errorf("Expected the rejected key to cost accuracy, got %+v", m)
+	}
+}
+
+func TestStopOnWord(t *testing.T) {
+	e := New("ab cd")
+	e.Rules = Rules{StopOnWord: true}
+
+	typeString(e, "ax ")
+	if string(e.UserInput) != "ax" {
+		t.Fatalf("Expected the space to be refused after a wrong word, got %q", string(e.UserInput))
+	}
+
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	typeString(e, " ")
+	if string(e.UserInput) != "a" {
+		t.Fatalf("Expected the space to be refused after a short word, got %q", string(e.UserInput))
+	}
+
+	typeString(e, "b cd")
+	if !e.IsFinished {
+		t.Errorf("Expected the session to finish, got %q", string(e.UserInput))
+	}
+}
+
+func TestNoBackspace(t *testing.T) {
+	e := New("abc")
+	e.Rules = Rules{NoBackspace: true}
+
+	typeString(e, "ax")
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyCtrlW})
+
+	if string(e.UserInput) != "ax" {
+		t.Errorf("Expected deletions to be ignored, got %q", string(e.UserInput))
+	}
+}
+
+func TestSuddenDeath(t *testing.T) {
+	e := New("abcdef")
+	e.Rules = Rules{SuddenDeath: true}
+
+	typeString(e, "abx")
+
+	if !e.IsFinished || !e.Failed {
+		t.Fatal("Expected the first mistake to end the session")
+	}
+	typeString(e, "cdef")
+	if string(e.UserInput) != "abx" {
+		t.Errorf("Expected no input after failing, got %q", string(e.UserInput))
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	for _, rules := range []Rules{
+		{},
+		{StopOnWord: true},
+		{StopOnLetter: true, NoBackspace: true, SuddenDeath: true},
+	} {
+		parsed, err := ParseRules(rules.String())
+		if err != nil || parsed != rules {
+			t.Errorf("Round trip of %q failed: %+v, %v", rules.String(), parsed, err)
+		}
+	}
+
+	if _, err := ParseRules("stop-on-sentence"); err == nil {
+		t.Error("Expected an unknown rule to be rejected")
+	}
+}
+
+func TestPassed(t *testing.T) {
+	e := New("abcd")
+	typeString(e, "abx")
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	typeString(e, "cd")
+
+	// One corrected mistake in five keys typed: 80% accuracy.
+	if !e.Passed(80) || e.Passed(95) {
+		t.Errorf("Expected the session to pass at 80%% but not at 95%%")
+	}
+
+	d := New("abcd")
+	d.Rules = Rules{SuddenDeath: true}
+	typeString(d, "x")
+	if d.Passed(0) {
+		t.Error("Expected a sudden-death failure never to pass")
+	}
+}
+
+func TestAutoIndent(t *testing.T) {
+	e := New("if x {\n\treturn\n}")
+	e.Indent = Indentation{Auto: true}
+
+	typeString(e, "if x {\n")
+	if string(e.UserInput) != "if x {\n\t" {
+		t.Fatalf("Expected the indentation to be filled after Enter, got %q", string(e.UserInput))
+	}
+
+	typeString(e, "r")
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	if string(e.UserInput) != "if x {\n\t" {
+		t.Fatalf("Expected backspace to stop at the indentation, got %q", string(e.UserInput))
+	}
+	e.ProcessKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	if string(e.UserInput) != "if x {" {
+		t.Fatalf("Expected backspace to undo the Enter, got %q", string(e.UserInput))
+	}
+
+	typeString(e, "\nreturn\n}")
+	if !e.IsFinished {
+		t.Fatalf("Expected the session to finish, got %q", string(e.UserInput))
+	}
+	for _, k := range e.Keystrokes {
+		if k.Typed == '\t' {
+			t.Errorf("Expected no keystroke for filled indentation, got %+v", k)
+		}
+	}
+
+	target, input := e.Scored()
+	if string(target) != "if x {\nreturn\n}" || string(input) != string(target) {
+		t.Errorf("Expected filled runes to be left out of scoring, got %q and %q", string(target), string(input))
+	}
+	if m := e.Metrics(); m.Accuracy != 100 || m.CorrectedErrors != 0 {
+		t.Errorf("Expected filled runes not to cost accuracy, got %+v", m)
+	}
+}
+
+func TestAutoIndentAfterMistake(t *testing.T) {
+	e := New("if x {\n\treturn y\n}")
+	e.Indent = Indentation{Auto: true}
+
+	// The skipped space must not stop the next line being indented.
+	typeString(e, "if x{\n")
+	if string(e.UserInput) != "if x{\n\t" {
+		t.Fatalf("Expected the indentation to be filled after a slip, got %q", string(e.UserInput))
+	}
+
+	typeString(e, "return y\n}")
+	target, input := e.Scored()
+	if string(target) != "if x {\nreturn y\n}" || string(input) != "if x{\nreturn y\n}" {
+		t.Errorf("Expected the filled tab to be left out of both sides, got %q and %q", string(target), string(input))
+	}
+	if e.ErrorCount != 1 {
+		t.Errorf("Expected 1 error, got %d", e.ErrorCount)
+	}
+}
+
+func TestCollapseTrailingAfterMistake(t *testing.T) {
+	e := New("ab  \nc")
+	e.Indent = Indentation{CollapseTrailing: true}
+
+	typeString(e, "b\nc")
+	if string(e.UserInput) != "b  \nc" {
+		t.Errorf("Expected Enter to skip the trailing spaces after a slip, got %q", string(e.UserInput))
+	}
+}
+
+func TestCollapseTrailing(t *testing.T) {
+	e := New("a  \nb")
+	e.Indent = Indentation{CollapseTrailing: true}
+
+	typeString(e, "a\nb")
+
+	if !e.IsFinished {
+		t.Fatalf("Expected Enter to skip the trailing spaces, got %q", string(e.UserInput))
+	}
+	if n := len(e.Keystrokes); n != 3 {
+		t.Errorf("Expected 3 keystrokes, got %d", n)
+	}
+}
@@ -0,0 +1,134 @@
+package engine
+
+// Indentation decides which whitespace of a code lesson the engine types
+// for the user, the way an editor would. The zero value leaves every rune
+// to the user.
+type Indentation struct {
+	// Auto fills a line's leading indentation once the line break before
+	// it is typed.
+	Auto bool
+	// CollapseTrailing lets Enter skip the whitespace left before a line
+	// break.
+	CollapseTrailing bool
+}
+
+func isIndent(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// autoType appends the target rune at pos on the user's behalf. Filled
+// runes are not keystrokes: they are left out of the log, and so of
+// accuracy, rhythm and latency, as well as of WPM and key stats.
+func (e *Engine) autoType(pos int) {
+	if e.filled == nil {
+		e.filled = make(map[int]int)
+	}
+	e.filled[len(e.UserInput)] = pos
+	e.UserInput = append(e.UserInput, e.TargetText[pos])
+}
+
+func (e *Engine) isFilled(pos int) bool {
+	_, ok := e.filled[pos]
+	return ok
+}
+
+// fillIndent types the indentation of the line the cursor starts, if its
+// line break was typed where the target has one. Positions in the target
+// come from the aligned cursor, so an earlier slip does not stop it.
+func (e *Engine) fillIndent() {
+	if !e.Indent.Auto {
+		return
+	}
+	n := len(e.UserInput)
+	if n > 0 && e.UserInput[n-1] != '\n' {
+		return
+	}
+	pos := e.aligned()
+	if pos > 0 && e.TargetText[pos-1] != '\n' {
+		return
+	}
+	for ; pos < len(e.TargetText) && isIndent(e.TargetText[pos]); pos++ {
+		e.autoType(pos)
+	}
+}
+
+// collapseTrailing types the whitespace between the cursor and the end of
+// its line, so that Enter lands on the line break.
+func (e *Engine) collapseTrailing() {
+	if !e.Indent.CollapseTrailing {
+		return
+	}
+	start := e.aligned()
+	end := start
+	for end < len(e.TargetText) && isIndent(e.TargetText[end]) {
+		end++
+	}
+	if end == start || end == len(e.TargetText) || e.TargetText[end] != '\n' {
+		return
+	}
+	for pos := start; pos < end; pos++ {
+		e.autoType(pos)
+	}
+}
+
+// unfill moves the end of a deletion down from n so that it never stops
+// after filled whitespace. When the deletion started on filled runes, the
+// line break that brought them in goes too, undoing the Enter. Leading
+// indentation is filled in again afterwards by fillIndent.
+func (e *Engine) unfill(n int) int {
+	removedFilled := e.isFilled(n)
+	for n > 0 && e.isFilled(n-1) {
+		n--
+	}
+	if removedFilled && n > 0 {
+		n--
+		for n > 0 && e.isFilled(n-1) {
+			n--
+		}
+	}
+	return n
+}
+
+// truncate cuts the input down to n runes.
+func (e *Engine) truncate(n int) {
+	for pos := range e.filled {
+		if pos >= n {
+			delete(e.filled, pos)
+		}
+	}
+	e.UserInput = e.UserInput[:n]
+}
+
+// filledBefore counts the filled runes among the first n of the target.
+func (e *Engine) filledBefore(n int) int {
+	count := 0
+	for _, pos := range e.filled {
+		if pos < n {
+			count++
+		}
+	}
+	return count
+}
+
+// Scored returns the target and the input without the runes the engine
+// filled in, which is what key and bigram stats are computed from.
+func (e *Engine) Scored() (target, input []rune) {
+	if len(e.filled) == 0 {
+		return e.TargetText, e.UserInput
+	}
+	inTarget := make(map[int]bool, len(e.filled))
+	for _, pos := range e.filled {
+		inTarget[pos] = true
+	}
+	for i, r := range e.TargetText {
+		if !inTarget[i] {
+			target = append(target, r)
+		}
+	}
+	for i, r := range e.UserInput {
+		if !e.isFilled(i) {
+			input = append(input, r)
+		}
+	}
+	return target, input
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"time"
+
+	"kata/pkg/align"
+)
+
+type KeystrokeKind int
+
+const (
+	// KeystrokeInsert is a rune typed at a position for the first time.
+	KeystrokeInsert KeystrokeKind = iota
+	// KeystrokeCorrection is a rune typed at a position that was previously
+	// typed and then deleted.
+	KeystrokeCorrection
+	// KeystrokeDelete is a rune removed by backspace or word deletion.
+	KeystrokeDelete
+	// KeystrokeRejected is a wrong rune that a strictness rule kept out of
+	// the input.
+	KeystrokeRejected
+)
+
+func (k KeystrokeKind) String() string {
+	switch k {
+	case KeystrokeCorrection:
+		return "correction"
+	case KeystrokeDelete:
+		return "delete"
+	case KeystrokeRejected:
+		return "rejected"
+	default:
+		return "insert"
+	}
+}
+
+// Keystroke is a single timestamped event in a practice session.
+// Expected is the target rune at the aligned cursor, 0 when the cursor lies
+// past the end of the target text or a deleted rune was an extra one.
+// For deletions Typed holds the rune that was removed.
+type Keystroke struct {
+	Time     time.Time
+	Position int
+	Expected rune
+	Typed    rune
+	Kind     KeystrokeKind
+}
+
+// Correct reports whether the typed rune matched the expected one.
+func (k Keystroke) Correct() bool {
+	return k.Expected != 0 && k.Typed == k.Expected
+}
+
+func (e *Engine) expectedAt(pos int) rune {
+	if pos < len(e.TargetText) {
+		return e.TargetText[pos]
+	}
+	return 0
+}
+
+// aligned brings the alignment up to date with the input and returns the
+// target position the next rune typed is aligned with.
+func (e *Engine) aligned() int {
+	e.calculateErrors()
+	return e.Cursor()
+}
+
+// expectedFor is the target rune input position pos was aligned with, or
+// 0 for an extra rune.
+func (e *Engine) expectedFor(pos int) rune {
+	for i := len(e.edits) - 1; i >= 0; i-- {
+		ed := e.edits[i]
+		if ed.Op == align.Omission || ed.InputPos > pos {
+			continue
+		}
+		switch ed.Op {
+		case align.Insertion:
+			return 0
+		case align.Transposition:
+			return e.expectedAt(ed.TargetPos + pos - ed.InputPos)
+		}
+		return ed.Expected
+	}
+	return 0
+}
+
+// recordInsert logs the rune just appended to the input. expected is the
+// target rune at the aligned cursor when it was typed, so a skipped or
+// doubled rune earlier on does not shift it.
+func (e *Engine) recordInsert(expected rune, now time.Time) {
+	i := len(e.UserInput) - 1
+	kind := KeystrokeInsert
+	if i < e.furthest {
+		kind = KeystrokeCorrection
+	}
+	e.Keystrokes = append(e.Keystrokes, Keystroke{
+		Time:     now,
+		Position: i,
+		Expected: expected,
+		Typed:    e.UserInput[i],
+		Kind:     kind,
+	})
+	if len(e.UserInput) > e.furthest {
+		e.furthest = len(e.UserInput)
+	}
+}
+
+func (e *Engine) recordDeletes(removed []rune, from int, now time.Time) {
+	// Deleted runes are logged last-to-first, the order they disappear.
+	// Whitespace the engine filled in was never typed, so nor is its removal.
+	for i := len(removed) - 1; i >= 0; i-- {
+		if e.isFilled(from + i) {
+			continue
+		}
+		e.Keystrokes = append(e.Keystrokes, Keystroke{
+			Time:     now,
+			Position: from + i,
+			Expected: e.expectedFor(from + i),
+			Typed:    removed[i],
+			Kind:     KeystrokeDelete,
+		})
+	}
+}
+
+func (e *Engine) recordRejected(r, expected rune, now time.Time) {
+	e.Keystrokes = append(e.Keystrokes, Keystroke{
+		Time:     now,
+		Position: len(e.UserInput),
+		Expected: expected,
+		Typed:    r,
+		Kind:     KeystrokeRejected,
+	})
+}
+
+// mistakeSince reports whether any rune typed since the first n keystrokes
+// were logged was wrong.
+func (e *Engine) mistakeSince(n int) bool {
+	for _, k := range e.Keystrokes[n:] {
+		if k.Kind != KeystrokeDelete && !k.Correct() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"math"
+	"time"
+)
+
+// Pauses longer than this are not part of the typing rhythm.
+const maxRhythmGap = 2 * time.Second
+
+// Metrics are the detailed results of a session.
+type Metrics struct {
+	// RawWPM counts every rune typed, including ones later deleted.
+	RawWPM float64
+	// NetWPM is the headline speed returned by GetStats.
+	NetWPM float64
+	// Accuracy is the share of typed runes that were not mistakes,
+	// corrected or not.
+	Accuracy float64
+	// CorrectedErrors are wrong runes that were deleted again.
+	CorrectedErrors int
+	// UncorrectedErrors are the mistakes still in the input (ErrorCount).
+	UncorrectedErrors int
+	// Consistency is 100 × (1 − coefficient of variation) of the time
+	// between key presses: 100 is a perfectly even rhythm.
+	Consistency float64
+	Duration    float64
+}
+
+// Metrics computes the detailed results from the keystroke log.
+func (e *Engine) Metrics() Metrics {
+	wpm, accuracy, duration := e.GetStats()
+
+	m := Metrics{
+		NetWPM:            wpm,
+		Accuracy:          accuracy,
+		UncorrectedErrors: e.ErrorCount,
+		Duration:          duration,
+	}
+	if duration == 0 {
+		return m
+	}
+
+	typed, corrected := e.keystrokeCounts()
+	m.CorrectedErrors = corrected
+	m.RawWPM = float64(typed) / 5.0 / duration * 60.0
+	m.Consistency = rhythmConsistency(e.Keystrokes)
+
+	return m
+}
+
+// Passed reports whether a finished session cleared the accuracy gate:
+// it was not ended by sudden death and reached threshold percent accuracy.
+func (e *Engine) Passed(threshold float64) bool {
+	if !e.IsFinished || e.Failed {
+		return false
+	}
+	_, accuracy, _ := e.GetStats()
+	return accuracy >= threshold
+}
+
+// typingAccuracy counts corrected and uncorrected mistakes against every
+// rune typed, so fixing an error does not erase it.
+func (e *Engine) typingAccuracy() (float64, bool) {
+	typed, corrected := e.keystrokeCounts()
+	if typed == 0 {
+		return 0, false
+	}
+
+	correct := max(0, typed-corrected-e.ErrorCount)
+	return float64(correct) / float64(typed) * 100.0, true
+}
+
+// keystrokeCounts returns how many runes were typed and how many wrong
+// runes were deleted again or rejected.
+func (e *Engine) keystrokeCounts() (typed, corrected int) {
+	for _, k := range e.Keystrokes {
+		switch k.Kind {
+		case KeystrokeDelete:
+			if !k.Correct() {
+				corrected++
+			}
+		case KeystrokeRejected:
+			// Kept out of the input, so counted as a corrected mistake.
+			typed++
+			corrected++
+		default:
+			typed++
+		}
+	}
+	return typed, corrected
+}
+
+func rhythmConsistency(keystrokes []Keystroke) float64 {
+	var gaps []float64
+	for i := 1; i < len(keystrokes); i++ {
+		gap := keystrokes[i].Time.Sub(keystrokes[i-1].Time)
+		// Events sharing a timestamp came from one key press.
+		if gap <= 0 || gap > maxRhythmGap {
+			continue
+		}
+		gaps = append(gaps, gap.Seconds())
+	}
+	if len(gaps) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, g := range gaps {
+		mean += g
+	}
+	mean /= float64(len(gaps))
+
+	variance := 0.0
+	for _, g := range gaps {
+		variance += (g - mean) * (g - mean)
+	}
+	cv := math.Sqrt(variance/float64(len(gaps))) / mean
+
+	return math.Max(0, 100*(1-cv))
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Rules are the strictness settings of a session. The zero value is the
+// standard, forgiving behaviour.
+type Rules struct {
+	// StopOnLetter keeps the cursor in place until the right key is hit.
+	StopOnLetter bool
+	// StopOnWord refuses the space (or newline) after a word until the
+	// word is typed correctly.
+	StopOnWord bool
+	// NoBackspace ignores every kind of deletion.
+	NoBackspace bool
+	// SuddenDeath fails the session on the first mistake.
+	SuddenDeath bool
+}
+
+// Rule names as used in config, on the command line and in stored sessions.
+const (
+	RuleStopOnLetter = "stop-on-letter"
+	RuleStopOnWord   = "stop-on-word"
+	RuleNoBackspace  = "no-backspace"
+	RuleSuddenDeath  = "sudden-death"
+)
+
+// RuleNames lists every rule in display order.
+var RuleNames = []string{RuleStopOnLetter, RuleStopOnWord, RuleNoBackspace, RuleSuddenDeath}
+
+// String is the tag stored with sessions: the enabled rules joined by
+// commas, or "standard" when none is.
+func (r Rules) String() string {
+	var names []string
+	for _, name := range RuleNames {
+		if r.Has(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "standard"
+	}
+	return strings.Join(names, ",")
+}
+
+// Has reports whether the named rule is enabled.
+func (r Rules) Has(name string) bool {
+	switch name {
+	case RuleStopOnLetter:
+		return r.StopOnLetter
+	case RuleStopOnWord:
+		return r.StopOnWord
+	case RuleNoBackspace:
+		return r.NoBackspace
+	case RuleSuddenDeath:
+		return r.SuddenDeath
+	}
+	return false
+}
+
+// Toggle flips the named rule.
+func (r *Rules) Toggle(name string) {
+	switch name {
+	case RuleStopOnLetter:
+		r.StopOnLetter = !r.StopOnLetter
+	case RuleStopOnWord:
+		r.StopOnWord = !r.StopOnWord
+	case RuleNoBackspace:
+		r.NoBackspace = !r.NoBackspace
+	case RuleSuddenDeath:
+		r.SuddenDeath = !r.SuddenDeath
+	}
+}
+
+// ParseRules reads a comma-separated list of rule names, as produced by
+// String. "" and "standard" are the zero value.
+func ParseRules(s string) (Rules, error) {
+	var r Rules
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "", "standard":
+			continue
+		case RuleStopOnLetter, RuleStopOnWord, RuleNoBackspace, RuleSuddenDeath:
+			r.Toggle(name)
+		default:
+			return Rules{}, fmt.Errorf("unknown rule %q (want %s)", name, strings.Join(RuleNames, ", "))
+		}
+	}
+	return r, nil
+}
+
+// accept decides whether a typed rune may enter the input at the cursor.
+func (e *Engine) accept(r rune) bool {
+	pos := len(e.UserInput)
+	expected := e.expectedAt(pos)
+
+	if e.Rules.StopOnLetter && r != expected {
+		return false
+	}
+	if e.Rules.StopOnWord && isSeparator(r) {
+		if r != expected {
+			return false
+		}
+		for i := pos - 1; i >= 0 && !isSeparator(e.TargetText[i]); i-- {
+			if e.UserInput[i] != e.TargetText[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isSeparator(r rune) bool {
+	return r == ' ' || r == '\n' || r == '\t'
+}
+
+// fail ends a sudden-death session.
+func (e *Engine) fail(now time.Time) {
+	e.IsFinished = true
+	e.Failed = true
+	e.EndTime = now
+}
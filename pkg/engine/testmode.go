@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TestKind int
+
+const (
+	// TestText ends when the whole text has been typed correctly.
+	TestText TestKind = iota
+	// TestTimed ends when the time runs out; the text is extended as needed.
+	TestTimed
+	// TestWords ends when the last word has been typed, mistakes or not.
+	TestWords
+)
+
+// TestMode is the shape of a session. The zero value is a plain lesson.
+type TestMode struct {
+	Kind     TestKind
+	Duration time.Duration // TestTimed
+	Words    int           // TestWords
+}
+
+// The standard test lengths offered in the UI.
+var (
+	TestDurations  = []time.Duration{15 * time.Second, 30 * time.Second, 60 * time.Second, 120 * time.Second}
+	TestWordCounts = []int{10, 25, 50, 100}
+)
+
+// lookahead is how many untyped runes a timed test keeps ahead of the
+// cursor before asking for more text.
+const lookahead = 80
+
+// String is the tag stored with sessions: "time:30", "words:25", or ""
+// for a plain lesson.
+func (t TestMode) String() string {
+	switch t.Kind {
+	case TestTimed:
+		return fmt.Sprintf("time:%d", int(t.Duration.Seconds()))
+	case TestWords:
+		return fmt.Sprintf("words:%d", t.Words)
+	}
+	return ""
+}
+
+// ParseTestMode reads a tag produced by String.
+func ParseTestMode(s string) (TestMode, error) {
+	if s == "" {
+		return TestMode{}, nil
+	}
+
+	kind, value, ok := strings.Cut(s, ":")
+	n, err := strconv.Atoi(value)
+	if !ok || err != nil || n <= 0 {
+		return TestMode{}, fmt.Errorf("invalid test mode %q (want time:<seconds> or words:<count>)", s)
+	}
+
+	switch kind {
+	case "time":
+		return TestMode{Kind: TestTimed, Duration: time.Duration(n) * time.Second}, nil
+	case "words":
+		return TestMode{Kind: TestWords, Words: n}, nil
+	}
+	return TestMode{}, fmt.Errorf("invalid test mode %q (want time:<seconds> or words:<count>)", s)
+}
+
+// NewTest starts an engine for the given test shape.
+func NewTest(targetText string, mode TestMode) *Engine {
+	e := New(targetText)
+	e.Test = mode
+	return e
+}
+
+// Tick ends a timed test once its time is up. The clock starts with the
+// first keystroke; ProcessKey checks it too, so keys pressed between two
+// ticks after the deadline are not counted.
+func (e *Engine) Tick(now time.Time) {
+	if e.Test.Kind != TestTimed || e.IsFinished || e.StartTime.IsZero() {
+		return
+	}
+	if now.Sub(e.StartTime) >= e.Test.Duration {
+		e.IsFinished = true
+		e.EndTime = e.StartTime.Add(e.Test.Duration)
+	}
+}
+
+// Remaining is the time left in a timed test.
+func (e *Engine) Remaining(now time.Time) time.Duration {
+	if e.Test.Kind != TestTimed {
+		return 0
+	}
+	if e.StartTime.IsZero() {
+		return e.Test.Duration
+	}
+	if e.IsFinished {
+		return 0
+	}
+	return max(0, e.Test.Duration-now.Sub(e.StartTime))
+}
+
+// NeedsMoreText reports whether a timed test is close to running out of
+// text.
+func (e *Engine) NeedsMoreText() bool {
+	return e.Test.Kind == TestTimed && !e.IsFinished &&
+		len(e.TargetText)-len(e.UserInput) < lookahead
+}
+
+// AppendText extends the target with another chunk of words.
+func (e *Engine) AppendText(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if len(e.TargetText) > 0 {
+		e.TargetText = append(e.TargetText, ' ')
+	}
+	e.TargetText = append(e.TargetText, []rune(text)...)
+}
+
+// Typed returns the text the session covered: a timed test keeps extending
+// its target ahead of the cursor, so only the part up to the cursor counts.
+func (e *Engine) Typed() string {
+	if e.Test.Kind != TestTimed {
+		return string(e.TargetText)
+	}
+	return string(e.TargetText[:min(e.Cursor(), len(e.TargetText))])
+}
+
+// WordsTyped counts the words of the target the cursor has moved past.
+func (e *Engine) WordsTyped() int {
+	n := min(len(e.UserInput), len(e.TargetText))
+	words := 0
+	for i := 0; i < n; i++ {
+		if e.TargetText[i] == ' ' || e.TargetText[i] == '\n' {
+			words++
+		}
+	}
+	if e.IsFinished && n == len(e.TargetText) && n > 0 {
+		words++
+	}
+	return words
+}
@@ -11,14 +11,16 @@ import (
 )
 
 type ExportData struct {
-	ExportDate    time.Time       `json:"export_date"`
-	AverageWPM    float64         `json:"average_wpm"`
-	Sessions      []stats.Session `json:"sessions"`
-	KeyStatistics []stats.KeyStat `json:"key_statistics"`
+	ExportDate    time.Time            `json:"export_date"`
+	AverageWPM    float64              `json:"average_wpm"`
+	ByMode        []stats.Aggregate    `json:"by_mode"`
+	PersonalBests []stats.PersonalBest `json:"personal_bests"`
+	Sessions      []stats.Session      `json:"sessions"`
+	KeyStatistics []stats.KeyStat      `json:"key_statistics"`
 }
 
-func ToJSON(db *stats.DB, outputFile string) error {
-	data, err := gatherData(db)
+func ToJSON(db *stats.DB, outputFile string, filter stats.SessionFilter) error {
+	data, err := gatherData(db, filter)
 	if err != nil {
 		return err
 	}
@@ -35,8 +37,8 @@ func ToJSON(db *stats.DB, outputFile string) error {
 	return nil
 }
 
-func ToCSV(db *stats.DB, outputFile string) error {
-	sessions, err := db.GetRecentSessions(1000) // Get all sessions
+func ToCSV(db *stats.DB, outputFile string, filter stats.SessionFilter) error {
+	sessions, err := db.QuerySessions(filter)
 	if err != nil {
 		return fmt.Errorf("failed to get sessions: %w", err)
 	}
@@ -50,7 +52,10 @@ func ToCSV(db *stats.DB, outputFile string) error {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	header := []string{"ID", "Timestamp", "WPM", "Accuracy", "Duration", "ErrorCount"}
+	header := []string{"ID", "Timestamp", "WPM", "RawWPM", "Accuracy", "Duration", "ErrorCount",
+		"CorrectedErrors", "Consistency",
+		"Mode", "Language", "Source", "SourceHash", "ZenMode", "Rules", "TestMode", "Passed",
+		"Substitutions", "Insertions", "Omissions", "Transpositions"}
 	if err := writer.Write(header); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
@@ -60,9 +65,24 @@ func ToCSV(db *stats.DB, outputFile string) error {
 			fmt.Sprintf("%d", s.ID),
 			s.Timestamp.Format(time.RFC3339),
 			fmt.Sprintf("%.2f", s.WPM),
+			fmt.Sprintf("%.2f", s.RawWPM),
 			fmt.Sprintf("%.2f", s.Accuracy),
 			fmt.Sprintf("%.2f", s.Duration),
 			fmt.Sprintf("%d", s.ErrorCount),
+			fmt.Sprintf("%d", s.CorrectedErrors),
+			fmt.Sprintf("%.1f", s.Consistency),
+			s.Mode,
+			s.Language,
+			s.Source,
+			s.SourceHash,
+			fmt.Sprintf("%t", s.ZenMode),
+			s.Rules,
+			s.TestMode,
+			fmt.Sprintf("%t", s.Passed),
+			fmt.Sprintf("%d", s.Substitutions),
+			fmt.Sprintf("%d", s.Insertions),
+			fmt.Sprintf("%d", s.Omissions),
+			fmt.Sprintf("%d", s.Transpositions),
 		}
 		if err := writer.Write(record); err != nil {
 			return fmt.Errorf("failed to write CSV record: %w", err)
@@ -72,17 +92,29 @@ func ToCSV(db *stats.DB, outputFile string) error {
 	return nil
 }
 
-func gatherData(db *stats.DB) (ExportData, error) {
+func gatherData(db *stats.DB, filter stats.SessionFilter) (ExportData, error) {
 	data := ExportData{
 		ExportDate: time.Now(),
 	}
 
-	avgWPM, err := db.GetAverageWPM()
-	if err == nil {
-		data.AverageWPM = avgWPM
+	overall, err := db.AggregateSessions(filter, stats.GroupNone, nil)
+	if err == nil && len(overall) > 0 {
+		data.AverageWPM = overall[0].MeanWPM
 	}
 
-	sessions, err := db.GetRecentSessions(1000)
+	byMode, err := db.AggregateSessions(filter, stats.GroupMode, nil)
+	if err != nil {
+		return data, fmt.Errorf("failed to aggregate sessions: %w", err)
+	}
+	data.ByMode = byMode
+
+	records, err := db.GetPersonalBests()
+	if err != nil {
+		return data, fmt.Errorf("failed to get personal bests: %w", err)
+	}
+	data.PersonalBests = records
+
+	sessions, err := db.QuerySessions(filter)
 	if err != nil {
 		return data, fmt.Errorf("failed to get sessions: %w", err)
 	}
@@ -0,0 +1,471 @@
+package extract
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// TabWidth is how many spaces a tab is worth when indentation is
+// normalised to spaces.
+const TabWidth = 4
+
+type style int
+
+const (
+	styleParagraphs style = iota // prose: split at blank lines
+	styleBraces                  // C-like: split at top-level blocks
+	styleIndent                  // Python-like: split at dedents
+)
+
+var braceExts = map[string]bool{
+	".go": true, ".c": true, ".h": true, ".cpp": true, ".cc": true, ".hpp": true,
+	".cs": true, ".java": true, ".kt": true, ".js": true, ".jsx": true, ".ts": true,
+	".tsx": true, ".rs": true, ".swift": true, ".scala": true, ".php": true, ".dart": true,
+}
+
+var indentExts = map[string]bool{
+	".py": true, ".pyi": true, ".nim": true, ".yaml": true, ".yml": true,
+}
+
+// usesTabs reports whether a file's convention is tab indentation.
+func usesTabs(path string) bool {
+	base := filepath.Base(path)
+	return filepath.Ext(path) == ".go" || base == "Makefile" || base == "makefile"
+}
+
+type line struct {
+	n    int // 1-based, in the original file
+	text string
+	// pad is the indentation dedentLines took off text, which is put back
+	// before the chunk is made and so still counts towards its length.
+	pad int
+}
+
+// Chunk splits a source file into lessons of at most opts.MaxLength runes,
+// cutting at top-level blocks: brace matching for C-like languages,
+// indentation for Python-like ones and blank lines for anything else.
+// Indentation is normalised to the language's convention and a leading
+// license header is dropped. Small neighbouring pieces are merged up to
+// the maximum, so the chunks cover the file in order. A single line longer
+// than the maximum is the only thing left whole.
+func Chunk(path, src string, opts Options) []Snippet {
+	lines := normalise(src, usesTabs(path))
+	lines = stripLicense(lines)
+
+	st := styleParagraphs
+	if ext := strings.ToLower(filepath.Ext(path)); braceExts[ext] {
+		st = styleBraces
+	} else if indentExts[ext] {
+		st = styleIndent
+	}
+
+	var snippets []Snippet
+	for _, r := range split(lines, st, opts.MaxLength) {
+		snippets = append(snippets, Snippet{Kind: KindChunk, Path: path, Line: r[0].n, Text: join(r)})
+	}
+	return snippets
+}
+
+func normalise(src string, tabs bool) []line {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	raw := strings.Split(src, "\n")
+	lines := make([]line, 0, len(raw))
+	for i, text := range raw {
+		text = strings.TrimRight(text, " \t\r")
+		body := strings.TrimLeft(text, " \t")
+		indent := text[:len(text)-len(body)]
+
+		spaces := strings.ReplaceAll(indent, "\t", strings.Repeat(" ", TabWidth))
+		if tabs {
+			indent = strings.Repeat("\t", len(spaces)/TabWidth) + strings.Repeat(" ", len(spaces)%TabWidth)
+		} else {
+			indent = spaces
+		}
+		lines = append(lines, line{n: i + 1, text: indent + body})
+	}
+	return lines
+}
+
+// stripLicense drops a comment block at the top of the file (after any
+// shebang) that mentions a license or copyright.
+func stripLicense(lines []line) []line {
+	start := 0
+	if len(lines) > 0 && strings.HasPrefix(lines[0].text, "#!") {
+		start = 1
+	}
+	for start < len(lines) && lines[start].text == "" {
+		start++
+	}
+	if start >= len(lines) {
+		return lines
+	}
+
+	end := start
+	first := strings.TrimSpace(lines[start].text)
+	switch {
+	case strings.HasPrefix(first, "/*"):
+		for end < len(lines) && !strings.Contains(lines[end].text, "*/") {
+			end++
+		}
+		end++
+	case strings.HasPrefix(first, `"""`) || strings.HasPrefix(first, "'''"):
+		quote := first[:3]
+		if strings.Count(first, quote) < 2 {
+			end++
+			for end < len(lines) && !strings.Contains(lines[end].text, quote) {
+				end++
+			}
+		}
+		end++
+	default:
+		for end < len(lines) && isLineComment(lines[end].text) {
+			end++
+		}
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if end == start {
+		return lines
+	}
+
+	var header strings.Builder
+	for _, l := range lines[start:end] {
+		header.WriteString(strings.ToLower(l.text))
+		header.WriteString("\n")
+	}
+	h := header.String()
+	if !strings.Contains(h, "license") && !strings.Contains(h, "copyright") && !strings.Contains(h, "spdx") {
+		return lines
+	}
+
+	for end < len(lines) && lines[end].text == "" {
+		end++
+	}
+	return append(append([]line(nil), lines[:start]...), lines[end:]...)
+}
+
+func isLineComment(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.HasPrefix(text, "//") || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "--")
+}
+
+// split cuts lines into top-level regions and merges neighbours up to
+// limit runes. A region longer than limit is split again inside its block,
+// then at blank lines, then by line.
+func split(lines []line, st style, limit int) [][]line {
+	var regions [][]line
+	switch st {
+	case styleBraces:
+		regions = braceRegions(lines)
+	case styleIndent:
+		regions = indentRegions(lines)
+	default:
+		regions = paragraphs(lines)
+	}
+
+	var out [][]line
+	var cur []line
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, cur)
+		}
+		cur = nil
+	}
+	for _, r := range regions {
+		if limit <= 0 || length(r) <= limit {
+			if len(cur) > 0 && limit > 0 && length(cur)+2+length(r) > limit {
+				flush()
+			}
+			cur = concat(cur, r)
+			continue
+		}
+
+		flush()
+		if head, inner, tail := body(r, st); st != styleParagraphs && len(inner) > 0 && len(inner) < len(r) {
+			inner, indent := dedentLines(inner)
+			pieces := split(inner, st, limit)
+			for _, p := range pieces {
+				for i := range p {
+					if p[i].text != "" {
+						p[i].text = indent + p[i].text
+						p[i].pad -= utf8.RuneCountInString(indent)
+					}
+				}
+			}
+			out = append(out, enclose(head, pieces, tail, limit)...)
+			continue
+		}
+		if paras := paragraphs(r); len(paras) > 1 {
+			for _, p := range paras {
+				out = append(out, split(p, styleParagraphs, limit)...)
+			}
+			continue
+		}
+		out = append(out, byLength(r, limit)...)
+	}
+	flush()
+	return out
+}
+
+// braceRegions cuts at blank lines and at lines that close a top-level
+// block.
+func braceRegions(lines []line) [][]line {
+	var regions [][]line
+	var cur []line
+	depth := 0
+	for _, l := range lines {
+		if depth == 0 && l.text == "" {
+			regions = appendRegion(regions, cur)
+			cur = nil
+			continue
+		}
+		cur = append(cur, l)
+
+		opened := depth > 0
+		delta, closes := braceDelta(l.text)
+		depth += delta
+		if depth < 0 {
+			depth = 0
+		}
+		if depth == 0 && (opened || closes) {
+			regions = appendRegion(regions, cur)
+			cur = nil
+		}
+	}
+	return appendRegion(regions, cur)
+}
+
+// braceDelta counts the braces a line opens minus those it closes,
+// ignoring strings, character literals and line comments, and reports
+// whether it closes any.
+func braceDelta(text string) (delta int, closes bool) {
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '{':
+			delta++
+		case '}':
+			delta--
+			closes = true
+		case '/':
+			if i+1 < len(runes) && runes[i+1] == '/' {
+				return delta, closes
+			}
+		case '"', '`':
+			i = closing(runes, i, r)
+		case '\'':
+			// An unmatched quote is a Rust lifetime, not a string.
+			if j := closing(runes, i, r); j < len(runes) {
+				i = j
+			}
+		}
+	}
+	return delta, closes
+}
+
+// closing returns the index of the quote that closes the one at i, or the
+// end of the line.
+func closing(runes []rune, i int, quote rune) int {
+	for j := i + 1; j < len(runes); j++ {
+		if runes[j] == '\\' {
+			j++
+			continue
+		}
+		if runes[j] == quote {
+			return j
+		}
+	}
+	return len(runes)
+}
+
+// indentRegions starts a region at each unindented line that follows a
+// blank or indented one, so comments, decorators and runs of imports stay
+// together, as do else/except clauses and closing brackets with the
+// statement they belong to.
+func indentRegions(lines []line) [][]line {
+	var regions [][]line
+	var cur []line
+	for i, l := range lines {
+		if i > 0 && startsRegion(lines[i-1].text, l.text) {
+			regions = appendRegion(regions, cur)
+			cur = nil
+		}
+		cur = append(cur, l)
+	}
+	return appendRegion(regions, cur)
+}
+
+func startsRegion(prev, text string) bool {
+	if text == "" || indentOf(text) != "" || continues(text) {
+		return false
+	}
+	return prev == "" || indentOf(prev) != ""
+}
+
+func continues(text string) bool {
+	for _, prefix := range []string{")", "]", "}", "else", "elif", "except", "finally"} {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func paragraphs(lines []line) [][]line {
+	var regions [][]line
+	var cur []line
+	for _, l := range lines {
+		if l.text == "" {
+			regions = appendRegion(regions, cur)
+			cur = nil
+			continue
+		}
+		cur = append(cur, l)
+	}
+	return appendRegion(regions, cur)
+}
+
+// body splits a block region into its header, its inside and its closing
+// lines: for braces the lines up to the opening brace, the lines between
+// and the closing line; for indentation the unindented lines, the rest and
+// nothing.
+func body(r []line, st style) (head, inner, tail []line) {
+	start := 0
+	switch st {
+	case styleBraces:
+		depth := 0
+		for start < len(r) && depth == 0 {
+			delta, _ := braceDelta(r[start].text)
+			depth += delta
+			start++
+		}
+	case styleIndent:
+		for start < len(r) && indentOf(r[start].text) == "" {
+			start++
+		}
+	}
+	end := len(r)
+	if st == styleBraces && end > start {
+		end--
+	}
+	if start >= end {
+		return r, nil, nil
+	}
+	return r[:start], r[start:end], r[end:]
+}
+
+// enclose puts a block's header and closing lines back around the pieces
+// cut from its inside, in the first and last piece where they fit and on
+// their own otherwise, so no line of the block is lost.
+func enclose(head []line, pieces [][]line, tail []line, limit int) [][]line {
+	if len(head) > 0 {
+		if len(pieces) > 0 && length(concat(head, pieces[0])) <= limit {
+			pieces[0] = concat(head, pieces[0])
+		} else {
+			pieces = append(byLength(head, limit), pieces...)
+		}
+	}
+	if len(tail) > 0 {
+		if last := len(pieces) - 1; last >= 0 && length(concat(pieces[last], tail)) <= limit {
+			pieces[last] = concat(pieces[last], tail)
+		} else {
+			pieces = append(pieces, byLength(tail, limit)...)
+		}
+	}
+	return pieces
+}
+
+// concat joins two runs of lines, keeping one blank line between them
+// where the file had any.
+func concat(a, b []line) []line {
+	out := append([]line(nil), a...)
+	if len(a) > 0 && len(b) > 0 && b[0].n > a[len(a)-1].n+1 {
+		out = append(out, line{n: b[0].n - 1})
+	}
+	return append(out, b...)
+}
+
+// dedentLines removes the indentation the lines have in common and
+// returns it.
+func dedentLines(lines []line) ([]line, string) {
+	common, seeded := "", false
+	for _, l := range lines {
+		if l.text == "" {
+			continue
+		}
+		indent := indentOf(l.text)
+		if !seeded {
+			common, seeded = indent, true
+			continue
+		}
+		for !strings.HasPrefix(indent, common) {
+			common = common[:len(common)-1]
+		}
+	}
+
+	out := make([]line, len(lines))
+	for i, l := range lines {
+		out[i] = l
+		if l.text != "" {
+			out[i].text = strings.TrimPrefix(l.text, common)
+			out[i].pad += utf8.RuneCountInString(common)
+		}
+	}
+	return out, common
+}
+
+// byLength cuts a region into runs of lines of at most limit runes each.
+func byLength(r []line, limit int) [][]line {
+	var out [][]line
+	var cur []line
+	for _, l := range r {
+		if len(cur) > 0 && length(cur)+1+length([]line{l}) > limit {
+			out = append(out, cur)
+			cur = nil
+		}
+		cur = append(cur, l)
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// appendRegion adds r to regions without its leading and trailing blank
+// lines, if anything is left.
+func appendRegion(regions [][]line, r []line) [][]line {
+	for len(r) > 0 && r[0].text == "" {
+		r = r[1:]
+	}
+	for len(r) > 0 && r[len(r)-1].text == "" {
+		r = r[:len(r)-1]
+	}
+	if len(r) == 0 {
+		return regions
+	}
+	return append(regions, r)
+}
+
+func indentOf(text string) string {
+	return text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+}
+
+func join(lines []line) string {
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = l.text
+	}
+	return strings.Join(texts, "\n")
+}
+
+// length is the rune count of the lines joined by newlines, once their
+// indentation is back.
+func length(lines []line) int {
+	n := len(lines) - 1
+	for _, l := range lines {
+		n += l.pad + utf8.RuneCountInString(l.text)
+	}
+	return n
+}
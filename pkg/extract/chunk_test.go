@@ -0,0 +1,193 @@
+package extract
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const pythonSource = "#!/usr/bin/env python3\n" +
+	"# Copyright 2024 Example Corp.\n" +
+	"# Licensed under the MIT License.\n" +
+	"\n" +
+	"import os\n" +
+	"import sys\n" +
+	"\n" +
+	"@cache\n" +
+	"def load(path):\n" +
+	"\twith open(path) as f:\n" +
+	"\t\treturn f.read()\n" +
+	"\n" +
+	"\n" +
+	"class Store:\n" +
+	"    def __init__(self, root):\n" +
+	"        self.root = root\n" +
+	"\n" +
+	"    def path(self, name):\n" +
+	"        return os.path.join(self.root, name)\n" +
+	"\n" +
+	"    def exists(self, name):\n" +
+	"        return os.path.exists(self.path(name))\n"
+
+func TestChunkPython(t *testing.T) {
+	chunks := Chunk("store.py", pythonSource, Options{MaxLength: 90})
+
+	var texts []string
+	for _, c := range chunks {
+		if strings.Contains(c.Text, "Copyright") {
+			t.Errorf("Expected the license header to be stripped, got %q", c.Text)
+		}
+		if strings.Contains(c.Text, "\t") {
+			t.Errorf("Expected tabs normalised to spaces, got %q", c.Text)
+		}
+		if n := len([]rune(c.Text)); n > 90 {
+			t.Errorf("Expected chunks of at most 90 runes, got %d: %q", n, c.Text)
+		}
+		texts = append(texts, c.Text)
+	}
+
+	if !strings.HasPrefix(texts[0], "#!/usr/bin/env python3\nimport os\nimport sys") {
+		t.Errorf("Expected the shebang and imports first, got %q", texts[0])
+	}
+	if !strings.Contains(strings.Join(texts, "\n"), "@cache\ndef load(path):\n    with open(path) as f:\n        return f.read()") {
+		t.Errorf("Expected the decorated function in one piece, got %q", texts)
+	}
+	// Store is too long for one chunk, so its methods are split out.
+	last := chunks[len(chunks)-1]
+	if last.Text != "    def exists(self, name):\n        return os.path.exists(self.path(name))" || last.Line != 21 {
+		t.Errorf("Expected the last method, as indented in the class, from line 21, got %d: %q", last.Line, last.Text)
+	}
+}
+
+const tsSource = `/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+export function greet(name: string): string {
+  const open = '{';
+  return ` + "`Hello, ${name}`" + `;
+}
+
+export class Counter {
+  private count = 0;
+
+  increment(): number {
+    return ++this.count;
+  }
+
+  reset(): void {
+    this.count = 0;
+  }
+}
+`
+
+func TestChunkBraces(t *testing.T) {
+	whole := Chunk("counter.ts", tsSource, Options{MaxLength: 1000})
+	if len(whole) != 1 || strings.Contains(whole[0].Text, "SPDX") || whole[0].Line != 5 {
+		t.Fatalf("Expected one chunk from line 5 without the header, got %v", whole)
+	}
+
+	chunks := Chunk("counter.ts", tsSource, Options{MaxLength: 100})
+	if len(chunks) != 3 {
+		t.Fatalf("Expected greet and Counter split in two, got %d: %v", len(chunks), chunks)
+	}
+	if !strings.HasPrefix(chunks[0].Text, "export function greet") || !strings.HasSuffix(chunks[0].Text, "}") {
+		t.Errorf("Expected greet whole despite the brace in a string, got %q", chunks[0].Text)
+	}
+	if chunks[2].Text != "  reset(): void {\n    this.count = 0;\n  }\n}" {
+		t.Errorf("Expected reset with the class's closing brace, got %q", chunks[2].Text)
+	}
+}
+
+func TestChunkCoversOversizedBlock(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("func long() {\n")
+	for i := 0; i < 12; i++ {
+		src.WriteString("\tif step(" + strings.Repeat("x", i) + ") {\n\t\treturn\n\t}\n")
+		if i%4 == 3 {
+			src.WriteString("\n")
+		}
+	}
+	src.WriteString("}")
+
+	chunks := Chunk("long.go", src.String(), Options{MaxLength: 120})
+	if len(chunks) < 3 {
+		t.Fatalf("Expected the function to be split, got %d chunks", len(chunks))
+	}
+
+	var joined []string
+	for _, c := range chunks {
+		joined = append(joined, c.Text)
+	}
+	// Chunks keep one blank line where the file had any, but none between
+	// them.
+	want := strings.ReplaceAll(src.String(), "\n\n", "\n")
+	if got := strings.ReplaceAll(strings.Join(joined, "\n"), "\n\n", "\n"); got != want {
+		t.Errorf("Expected the chunks to cover the function in order, got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestChunkLengthBound(t *testing.T) {
+	var py strings.Builder
+	py.WriteString("class Runner:\n")
+	for m := 0; m < 3; m++ {
+		py.WriteString("    def step" + strconv.Itoa(m) + "(self, items):\n")
+		py.WriteString("        for item in items:\n")
+		for i := 0; i < 20; i++ {
+			py.WriteString("            if item.ready(" + strconv.Itoa(i) + "):\n")
+			py.WriteString("                self.results.append(item.value * " + strconv.Itoa(i) + ")\n")
+		}
+		py.WriteString("\n")
+	}
+
+	files := map[string]string{"runner.py": py.String()}
+	for _, path := range []string{"../../internal/cli/cli.go", "../../internal/app/tui_init.go"} {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[path] = string(src)
+	}
+
+	for path, src := range files {
+		for _, c := range Chunk(path, src, DefaultOptions) {
+			if n := utf8.RuneCountInString(c.Text); n > DefaultOptions.MaxLength {
+				t.Errorf("%s: expected at most %d runes, got %d", c.Source(), DefaultOptions.MaxLength, n)
+			}
+		}
+	}
+}
+
+func TestBraceDelta(t *testing.T) {
+	tests := []struct {
+		line  string
+		delta int
+	}{
+		{"fn longer<'a>(x: &'a str) -> &'a str {", 1},
+		{`if s == "}" { // }`, 1},
+		{"} else {", 0},
+		{"let c = '{';", 0},
+	}
+	for _, tt := range tests {
+		if delta, _ := braceDelta(tt.line); delta != tt.delta {
+			t.Errorf("braceDelta(%q) = %d, expected %d", tt.line, delta, tt.delta)
+		}
+	}
+}
+
+func TestChunkProse(t *testing.T) {
+	text := "One short paragraph.\n\nAnother one.\n\n" + strings.Repeat("word word word\n", 10)
+	chunks := Chunk("notes.txt", text, Options{MaxLength: 60})
+	if len(chunks) != 4 || chunks[0].Text != "One short paragraph.\n\nAnother one." {
+		t.Errorf("Expected short paragraphs merged and the long one cut, got %q", chunks)
+	}
+}
+
+func TestChunkKeepsGoTabs(t *testing.T) {
+	chunks := Chunk("main.go", "package main\n\nfunc main() {\n    run()\n}\n", Options{MaxLength: 600})
+	if len(chunks) != 1 || chunks[0].Text != "package main\n\nfunc main() {\n\trun()\n}" {
+		t.Errorf("Expected Go indentation as tabs, got %q", chunks)
+	}
+}
@@ -0,0 +1,245 @@
+// Package extract turns a Go module into code lessons: function
+// declarations, struct definitions and error-handling blocks taken from
+// the real source.
+package extract
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+type Kind int
+
+const (
+	KindFunc Kind = iota
+	KindStruct
+	KindErrorBlock
+	KindChunk // cut from a file by Chunk rather than parsed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindFunc:
+		return "func"
+	case KindStruct:
+		return "struct"
+	case KindErrorBlock:
+		return "error handling"
+	case KindChunk:
+		return "chunk"
+	default:
+		return "unknown"
+	}
+}
+
+type Snippet struct {
+	Kind Kind
+	Path string // relative to the walked directory
+	Line int
+	Text string
+}
+
+// Source is where the snippet came from, e.g. "internal/cli/cli.go:42".
+func (s Snippet) Source() string {
+	return fmt.Sprintf("%s:%d", s.Path, s.Line)
+}
+
+// Options bound the length of a snippet, in runes.
+type Options struct {
+	MinLength int
+	MaxLength int
+}
+
+var DefaultOptions = Options{MinLength: 80, MaxLength: 600}
+
+// Repo walks the Go files under dir and returns every snippet of the
+// target length. Vendored code, testdata, hidden directories and
+// generated files are skipped, as are files that do not parse and entries
+// that cannot be read, such as a broken symlink or a directory without
+// permission. Only a dir that cannot be walked at all is an error.
+func Repo(dir string, opts Options) ([]Snippet, error) {
+	var snippets []Snippet
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(name) != ".go" {
+			return nil
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+		found, err := File(filepath.ToSlash(rel), src, opts)
+		if err != nil {
+			return nil
+		}
+		snippets = append(snippets, found...)
+		return nil
+	})
+	return snippets, err
+}
+
+// File extracts the snippets of one Go source file. Generated files yield
+// none.
+func File(path string, src []byte, opts Options) ([]Snippet, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if ast.IsGenerated(file) {
+		return nil, nil
+	}
+
+	var snippets []Snippet
+	add := func(kind Kind, from, to token.Pos) {
+		text := dedent(src, fset.Position(from).Offset, fset.Position(to).Offset)
+		if n := utf8.RuneCountInString(text); n < opts.MinLength || (opts.MaxLength > 0 && n > opts.MaxLength) {
+			return
+		}
+		snippets = append(snippets, Snippet{Kind: kind, Path: path, Line: fset.Position(from).Line, Text: text})
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Body != nil {
+				add(KindFunc, n.Pos(), n.End())
+			}
+		case *ast.GenDecl:
+			if n.Tok != token.TYPE {
+				break
+			}
+			for _, spec := range n.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if _, ok := ts.Type.(*ast.StructType); !ok {
+					continue
+				}
+				if len(n.Specs) == 1 {
+					add(KindStruct, n.Pos(), n.End())
+				} else {
+					add(KindStruct, ts.Pos(), ts.End())
+				}
+			}
+		case *ast.BlockStmt:
+			for i, stmt := range n.List {
+				check, ok := stmt.(*ast.IfStmt)
+				if !ok || !checksErr(check) {
+					continue
+				}
+				// Take the call that produced the error along with it.
+				from := check.Pos()
+				if check.Init == nil && i > 0 && assignsErr(n.List[i-1]) {
+					from = n.List[i-1].Pos()
+				}
+				add(KindErrorBlock, from, check.End())
+			}
+		}
+		return true
+	})
+	return snippets, nil
+}
+
+// checksErr reports whether an if statement is an `err != nil` check.
+func checksErr(s *ast.IfStmt) bool {
+	cond, ok := s.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ {
+		return false
+	}
+	nilIdent, ok := cond.Y.(*ast.Ident)
+	return ok && nilIdent.Name == "nil" && isErr(cond.X)
+}
+
+func assignsErr(s ast.Stmt) bool {
+	assign, ok := s.(*ast.AssignStmt)
+	if !ok {
+		return false
+	}
+	for _, lhs := range assign.Lhs {
+		if isErr(lhs) {
+			return true
+		}
+	}
+	return false
+}
+
+func isErr(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && (id.Name == "err" || strings.HasSuffix(id.Name, "Err"))
+}
+
+// dedent returns src[from:to] with the indentation of its first line
+// removed from every line.
+func dedent(src []byte, from, to int) string {
+	start := from
+	for start > 0 && (src[start-1] == '\t' || src[start-1] == ' ') {
+		start--
+	}
+	indent := string(src[start:from])
+
+	lines := strings.Split(string(src[from:to]), "\n")
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimPrefix(lines[i], indent)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Pick draws n distinct snippets, favouring those that contain weak keys:
+// each weak key a snippet contains adds one to its weight.
+func Pick(snippets []Snippet, weakKeys []string, n int, rng *rand.Rand) []Snippet {
+	pool := append([]Snippet(nil), snippets...)
+	weights := make([]float64, len(pool))
+	for i, s := range pool {
+		weights[i] = 1
+		for _, key := range weakKeys {
+			if key != "" && strings.Contains(s.Text, key) {
+				weights[i]++
+			}
+		}
+	}
+
+	var picked []Snippet
+	for len(picked) < n && len(pool) > 0 {
+		var total float64
+		for _, w := range weights {
+			total += w
+		}
+		x := rng.Float64() * total
+		i := 0
+		for ; i < len(pool)-1; i++ {
+			x -= weights[i]
+			if x < 0 {
+				break
+			}
+		}
+
+		picked = append(picked, pool[i])
+		pool = append(pool[:i], pool[i+1:]...)
+		weights = append(weights[:i], weights[i+1:]...)
+	}
+	return picked
+}
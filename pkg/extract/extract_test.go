@@ -0,0 +1,127 @@
+package extract
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sample = `package sample
+
+type Config struct {
+	Name string
+	Path string
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	return parse(data), nil
+}
+
+func noop() {}
+`
+
+func TestFile(t *testing.T) {
+	snippets, err := File("sample.go", []byte(sample), Options{MinLength: 20})
+	if err != nil {
+		t.Fatalf("File failed: %v", err)
+	}
+
+	byKind := make(map[Kind][]Snippet)
+	for _, s := range snippets {
+		byKind[s.Kind] = append(byKind[s.Kind], s)
+	}
+
+	if len(byKind[KindStruct]) != 1 || !strings.HasPrefix(byKind[KindStruct][0].Text, "type Config struct {") {
+		t.Errorf("Expected the Config struct, got %v", byKind[KindStruct])
+	}
+	if len(byKind[KindFunc]) != 1 || byKind[KindFunc][0].Line != 8 {
+		t.Errorf("Expected Load at line 8 and noop too short, got %v", byKind[KindFunc])
+	}
+
+	want := "data, err := os.ReadFile(path)\nif err != nil {\n\treturn nil, fmt.Errorf(\"read config: %w\", err)\n}"
+	if len(byKind[KindErrorBlock]) != 1 || byKind[KindErrorBlock][0].Text != want {
+		t.Errorf("Expected a dedented error block with its call, got %v", byKind[KindErrorBlock])
+	}
+
+	if snippets, _ := File("sample.go", []byte(sample), Options{MinLength: 20, MaxLength: 60}); len(snippets) != 1 {
+		t.Errorf("Expected only the struct under 60 runes, got %d snippets", len(snippets))
+	}
+}
+
+func TestRepoSkipsGeneratedAndVendored(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.go":           sample,
+		"gen.go":              "// Code generated by stringer. DO NOT EDIT.\n\n" + sample,
+		"vendor/lib/lib.go":   sample,
+		"testdata/bad.go":     sample,
+		"internal/broken.go":  "package broken\n\nfunc {",
+		"internal/sub/sub.go": sample,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snippets, err := Repo(dir, Options{MinLength: 20})
+	if err != nil {
+		t.Fatalf("Repo failed: %v", err)
+	}
+	paths := make(map[string]int)
+	for _, s := range snippets {
+		paths[s.Path]++
+	}
+	if len(paths) != 2 || paths["config.go"] != 3 || paths["internal/sub/sub.go"] != 3 {
+		t.Errorf("Expected snippets from config.go and internal/sub/sub.go only, got %v", paths)
+	}
+}
+
+func TestRepoSkipsUnreadableEntries(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.go"), []byte(sample), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing.go"), filepath.Join(dir, "broken.go")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	snippets, err := Repo(dir, Options{MinLength: 20})
+	if err != nil {
+		t.Fatalf("Expected a broken symlink to be skipped, got %v", err)
+	}
+	if len(snippets) != 3 {
+		t.Errorf("Expected the 3 snippets of config.go, got %d", len(snippets))
+	}
+
+	if _, err := Repo(filepath.Join(dir, "missing"), Options{MinLength: 20}); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+func TestPickPrefersWeakKeys(t *testing.T) {
+	snippets := []Snippet{{Text: "plain"}, {Text: "has {braces}"}}
+	rng := rand.New(rand.NewSource(1))
+
+	braces := 0
+	for i := 0; i < 300; i++ {
+		if Pick(snippets, []string{"{", "}"}, 1, rng)[0].Text == "has {braces}" {
+			braces++
+		}
+	}
+	if braces < 150 || braces > 250 {
+		t.Errorf("Expected braces about 3 times in 4, got %d/300", braces)
+	}
+
+	if picked := Pick(snippets, nil, 5, rng); len(picked) != 2 || picked[0].Text == picked[1].Text {
+		t.Errorf("Expected both snippets once each, got %v", picked)
+	}
+}
@@ -1,8 +1,10 @@
 package generator
 
 import (
+	"math"
 	"math/rand"
 	"os"
+	"sort"
 	"strings"
 	"time"
 )
@@ -16,8 +18,38 @@ const (
 	TypeCode
 	TypeFile
 	TypeWeaknesses
+	TypeKeys
+	TypeRepo
 )
 
+var lessonTypeNames = map[LessonType]string{
+	TypeBigrams:    "bigrams",
+	TypeWords:      "keywords",
+	TypeSymbols:    "symbols",
+	TypeCode:       "code",
+	TypeFile:       "file",
+	TypeWeaknesses: "weaknesses",
+	TypeKeys:       "keys",
+	TypeRepo:       "repo",
+}
+
+func (t LessonType) String() string {
+	if name, ok := lessonTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseLessonType is the inverse of LessonType.String.
+func ParseLessonType(name string) (LessonType, bool) {
+	for t, n := range lessonTypeNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
 type Language string
 
 const (
@@ -35,17 +67,32 @@ const (
 type Generator struct {
 	rand     *rand.Rand
 	Language Language
+
+	// Pseudo-word models: one per language, trained lazily, unless the
+	// user's corpus replaces them.
+	models      map[Language]*Markov
+	corpus      *Markov
+	weakBigrams map[string]float64
+	// WeakBias is how strongly pseudo-words favour weakBigrams.
+	WeakBias float64
+	// TopWords limits word lessons to the most frequent words; 0 uses
+	// the whole list.
+	TopWords int
 }
 
 type WeakKey struct {
 	Key       string
 	ErrorRate float64
+	// ConfusedWith is the key typed by mistake for Key, if known. The
+	// lesson then drills the two side by side.
+	ConfusedWith string
 }
 
 func New() *Generator {
 	return &Generator{
 		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
 		Language: LangGo,
+		WeakBias: DefaultWeakBias,
 	}
 }
 
@@ -74,52 +121,53 @@ var bigramsCode = []string{
 	"if", "in", "er", "re", "on", "at", "en", "is", "st", "or", "it", "to", "nt", "ng", "co", "de", "th", "he", "fu", "nc", "ct", "ti", "io", "ur", "rn", "rr", "ni", "il", "pa", "ac", "ck", "ka", "ag", "ge", "tr", "ru", "uc", "ty", "pe",
 }
 
+// Word and keyword lists are ordered from the most to the least frequent,
+// the spoken languages after film-subtitle and general-corpus frequency
+// lists, the keywords after their rough usage in open-source code. Lessons
+// draw from them by rank, so keep new entries in order and unique.
 var spanishWords = []string{
-	"que", "de", "no", "a", "la", "el", "es", "y", "en", "lo", "un", "por", "qué", "me", "una", "te", "los", "se", "con", "para", "mi", "está", "si", "bien", "pero", "yo", "eso", "las", "sí", "su", "tu", "aquí", "del", "al", "como", "le", "más", "esto", "ya", "todo", "esta", "vamos", "muy", "hay", "ahora", "algo", "estoy", "tengo", "nos", "tú", "nada", "cuando", "ha", "este", "sé", "estás", "así", "puedo", "cómo", "quiero", "sólo", "soy", "tiene", "gracias", "o", "él", "bueno", "fue", "ser", "hacer", "son", "todos", "era", "eres", "vez", "tienes", "creo", "ella", "he", "ese", "voy", "puede", "sabes", "hola", "sus", "porque", "dios", "quién", "nunca", "dónde", "quieres", "casa", "favor", "esa", "dos", "tan", "señor", "tiempo", "verdad", "estaba", "mejor", "están", "va", "hombre", "usted", "mucho", "hace", "entonces", "siento", "tenemos", "picos", "donde",
-	"vida", "ver", "alguien", "siempre", "hasta", "sin", "mismo", "han", "trabajo", "noche", "mundo", "parte", "padre", "tres", "gente", "decir", "hijo", "ni", "realmente", "amor", "lugar", "dinero", "buena", "amigo", "gran", "nuevo", "cosa", "tipo", "mira", "después", "mañana", "nuestro", "hizo", "madre", "nadie", "dicen", "mal", "hoy", "nosotros", "mía", "otras", "fuego", "cabeza", "cualquier", "manera", "día", "fin", "sola", "seguro", "historia", "luego", "cuenta", "quizás", "mujer", "claro", "año", "menos", "familia", "importa", "contar", "problema", "razón", "agua", "papá", "poder", "cinco", "fuerza", "ciudad", "guerra", "mano", "nombre", "chica", "cuerpo", "lado", "sido", "hablar", "hombres", "tarde", "estas", "único", "muerte", "chico", "minutos", "frente", "pueblo", "sueño", "quien", "fuerte", "ojos", "país", "semana", "mes", "cerca", "libro", "voz", "escuela", "juego", "puerta", "camino", "aire", "coche", "tierra", "paso", "sol", "luz", "alma", "alto", "rojo", "causa", "papel", "joven", "punto", "color", "cara", "final", "grande", "vivir", "pasar", "comer", "salir", "entrar", "tomar", "dormir", "morir", "leer", "cambiar", "sentir",
+	"que", "de", "no", "a", "la", "el", "es", "y", "en", "lo", "un", "por", "qué", "me", "una", "te", "los", "se", "con", "para", "mi", "está", "si", "bien", "pero", "yo", "eso", "las", "sí", "su", "tu", "aquí", "del", "al", "como", "le", "más", "esto", "ya", "todo", "esta", "vamos", "muy", "hay", "ahora", "algo", "estoy", "tengo", "nos", "tú", "nada", "cuando", "ha", "este", "sé", "estás", "así", "puedo", "cómo", "quiero", "sólo", "soy", "tiene", "gracias", "o", "él", "bueno", "fue", "ser", "hacer", "son", "todos", "era", "eres", "vez", "tienes", "creo", "ella", "he", "ese", "voy", "puede", "sabes", "hola", "sus", "porque", "dios", "quién", "nunca", "dónde", "quieres", "casa", "favor", "esa", "dos", "tan", "señor", "tiempo", "verdad", "estaba", "mejor", "están", "va", "hombre", "usted", "mucho", "hace", "entonces", "siento", "tenemos", "donde",
+	"puedes", "ahí", "ti", "vida", "ver", "alguien", "hasta", "sin", "mí", "solo", "años", "sobre", "decir", "uno", "siempre", "ni", "hecho", "mismo", "ir", "también", "nadie", "otra", "día", "estamos", "padre", "mañana", "noche", "mal", "después", "nuevo", "hijo", "cosas", "mundo", "trabajo", "madre", "dinero", "gente", "tres", "amigo", "parte", "hoy", "claro", "cosa", "mira", "hablar", "momento", "nombre", "mujer", "tipo", "tarde", "razón", "manera", "chica", "amor", "buena", "cabeza", "familia", "lugar", "problema", "agua", "papá", "menos", "realmente", "luego", "seguro", "gran", "cuenta", "nosotros", "quizás", "historia", "poder", "chico", "muerte", "guerra", "hombres", "cualquier", "ciudad", "cuerpo", "minutos", "nuestro", "fin", "cinco", "año", "ojos", "mano", "lado", "fuego", "país", "semana", "fuerte", "único", "cerca", "puerta", "juego", "importa", "frente", "camino", "coche", "final", "cara", "tierra", "fuerza", "escuela", "libro", "mes", "grande", "pueblo", "sueño", "voz", "luz", "joven", "punto", "aire", "paso", "alma", "causa", "sol", "color", "papel", "alto", "rojo", "vivir", "comer", "salir", "pasar", "dormir", "morir", "tomar", "cambiar", "sentir", "entrar", "leer", "contar",
 }
 
 var frenchWords = []string{
 	"je", "de", "est", "pas", "le", "vous", "la", "tu", "que", "un", "il", "et", "à", "a", "ne", "les", "en", "ce", "ça", "une", "ai", "pour", "on", "moi", "des", "mais", "bien", "du", "nous", "y", "me", "dans", "c'est", "elle", "si", "tout", "plus", "non", "mon", "suis", "te", "au", "avec", "va", "qui", "oui", "fait", "ils", "faire", "ma", "comme", "être", "sur", "quoi", "toi", "ici", "rien", "dit", "lui", "votre", "sais", "bon", "là", "pourquoi", "quand", "as", "étais", "peux", "son", "aussi", "avez", "par", "voir", "merci", "ont", "jamais", "où", "aller", "sont", "cette", "dire", "se", "veux", "tous", "m'a", "peut", "comment", "très", "même", "ta", "chose",
-	"vie", "deux", "temps", "peu", "homme", "monde", "encore", "vrai", "notre", "mal", "sa", "faut", "fois", "sans", "grand", "toujours", "elle", "seul", "autre", "femme", "après", "trouver", "jour", "ans", "père", "mère", "fils", "fille", "gens", "trop", "mes", "besoin", "accord", "ses", "mieux", "tes", "voir", "mort", "nuit", "main", "place", "beau", "maison", "nom", "travail", "trois", "petit", "choses", "personne", "heure", "avoir", "juste", "crois", "ton", "toute", "alors", "bonne", "penser", "prendre", "venir", "regarder", "demander", "laisser", "partir", "mettre", "rester", "aimer", "pouvoir", "vouloir", "devoir", "savoir", "donner", "comprendre", "connaître", "entendre", "parler", "jouer", "passer", "travailler", "manger", "dormir", "boire", "lire", "écrire", "acheter", "payer", "aider", "attendre", "finir", "perdre", "gagner", "sentir", "vivre", "mourir", "ouvrir", "fermer", "chercher", "trouver", "marcher", "courir", "sauter", "danser", "chanter", "rire", "pleurer", "sourire", "apprendre", "enseigner", "étudier", "oublier", "rappeler",
+	"ton", "alors", "avoir", "vais", "été", "vraiment", "allez", "sa", "faut", "peu", "encore", "deux", "fois", "crois", "temps", "sans", "autre", "toujours", "mes", "juste", "besoin", "accord", "trop", "mal", "après", "homme", "vie", "monde", "vrai", "notre", "grand", "jour", "ans", "toute", "père", "mère", "seul", "gens", "mieux", "ses", "tes", "fille", "fils", "femme", "nuit", "mort", "trouver", "petit", "maison", "heure", "choses", "personne", "place", "main", "nom", "travail", "trois", "beau", "bonne", "prendre", "venir", "parler", "partir", "penser", "mettre", "attendre", "regarder", "rester", "demander", "laisser", "comprendre", "aimer", "vivre", "savoir", "pouvoir", "vouloir", "donner", "devoir", "passer", "mourir", "entendre", "jouer", "chercher", "aider", "perdre", "manger", "oublier", "dormir", "payer", "travailler", "connaître", "gagner", "boire", "écrire", "lire", "sentir", "finir", "acheter", "ouvrir", "rappeler", "courir", "apprendre", "fermer", "marcher", "danser", "chanter", "pleurer", "rire", "sourire", "étudier", "sauter", "enseigner",
 }
 
 var germanWords = []string{
-	"ich", "ist", "nicht", "das", "du", "es", "sie", "und", "der", "wir", "was", "ein", "zu", "er", "in", "mir", "mit", "ja", "wie", "den", "auf", "mich", "dass", "aber", "eine", "so", "hat", "hier", "habe", "für", "sind", "wenn", "nein", "von", "dich", "war", "haben", "an", "einen", "uns", "da", "hab", "bin", "noch", "dir", "man", "nur", "sich", "ihr", "kann", "dem", "muss", "schon", "wer", "sein", "jetzt", "dann", "die", "immer", "mal", "wird", "als", "nichts", "alles", "doch", "gut", "nach", "aus", "um", "mein", "also", "ihm", "weiß", "wieder", "tun", "will", "keine", "geht", "mehr", "warum", "gesagt", "morgen", "bitte", "vor", "bei", "alle", "einer", "los", "vielleicht", "wäre", "wo", "hast", "also",
-	"Zeit", "Jahr", "Mensch", "Leben", "Kind", "Tag", "Hand", "Auge", "Land", "Wort", "Gott", "Welt", "Stadt", "Frau", "Herr", "Mann", "Haus", "Ding", "Ende", "Fall", "Weg", "Teil", "Woche", "Name", "Vater", "Mutter", "Sohn", "Tochter", "Freund", "Arbeit", "Geld", "Leute", "Nacht", "Wasser", "Seite", "Schule", "Problem", "Angst", "Recht", "Stunde", "Kopf", "Bild", "Buch", "Tisch", "Stuhl", "Raum", "Platz", "Musik", "Kunst", "Spiel", "Film", "Brief", "Farbe", "Licht", "Luft", "Boden", "Feuer", "Holz", "Stein", "Glas", "Gold", "Silber", "Eisen", "Stahl", "Kupfer", "Blei", "Zinn", "Zink", "Aluminium", "Bronze", "Messing", "Nickel", "Chrom", "Titan", "Platin", "Uran", "Plutonium", "Helium", "Neon", "Argon", "Krypton", "Xenon", "Radon", "Wasserstoff", "Sauerstoff", "Stickstoff", "Kohlenstoff", "Schwefel", "Phosphor", "Chlor", "Jod", "Fluor", "Brom", "gehen", "kommen", "sehen", "hören", "sagen", "machen", "geben", "nehmen", "lassen", "stehen", "liegen", "sitzen", "bleiben", "glauben", "denken", "wissen", "kennen", "verstehen", "lernen", "arbeiten", "spielen", "singen", "laufen", "fahren", "fliegen", "schwimmen", "essen", "trinken", "schlafen", "lesen", "schreiben", "malen", "bauen", "kaufen",
+	"ich", "ist", "nicht", "das", "du", "es", "sie", "und", "der", "wir", "was", "ein", "zu", "er", "in", "mir", "mit", "ja", "wie", "den", "auf", "mich", "dass", "aber", "eine", "so", "hat", "hier", "habe", "für", "sind", "wenn", "nein", "von", "dich", "war", "haben", "an", "einen", "uns", "da", "hab", "bin", "noch", "dir", "man", "nur", "sich", "ihr", "kann", "dem", "muss", "schon", "wer", "sein", "jetzt", "dann", "die", "immer", "mal", "wird", "als", "nichts", "alles", "doch", "gut", "nach", "aus", "um", "mein", "also", "ihm", "weiß", "wieder", "tun", "will", "keine", "geht", "mehr", "warum", "gesagt", "morgen", "bitte", "vor", "bei", "alle", "einer", "los", "vielleicht", "wäre", "wo", "hast",
+	"gibt", "weißt", "etwas", "heute", "sehr", "oder", "danke", "wirklich", "machen", "gehen", "sagen", "kommen", "sehen", "wissen", "Zeit", "Mann", "Leben", "geben", "lassen", "Frau", "Herr", "glauben", "Gott", "Tag", "denken", "Leute", "Geld", "nehmen", "Vater", "Haus", "bleiben", "Mutter", "Welt", "Nacht", "verstehen", "Jahr", "Kind", "Weg", "hören", "Ende", "Freund", "stehen", "Arbeit", "Hand", "Name", "kennen", "Sohn", "Kopf", "Ding", "Problem", "Angst", "Stadt", "Tochter", "Teil", "Recht", "Stunde", "essen", "Wort", "Fall", "Auge", "Land", "Mensch", "Woche", "Wasser", "Schule", "spielen", "liegen", "sitzen", "trinken", "schlafen", "arbeiten", "fahren", "Seite", "Platz", "Spiel", "Bild", "Buch", "Film", "laufen", "lernen", "kaufen", "Musik", "Brief", "Licht", "lesen", "schreiben", "Raum", "Feuer", "Tisch", "Luft", "fliegen", "singen", "Boden", "Stuhl", "Farbe", "Glas", "Gold", "Stein", "Kunst", "Holz", "bauen", "schwimmen", "malen",
 }
 
 var englishWords = []string{
 	"the", "be", "to", "of", "and", "a", "in", "that", "have", "i", "it", "for", "not", "on", "with", "he", "as", "you", "do", "at", "this", "but", "his", "by", "from", "they", "we", "say", "her", "she", "or", "an", "will", "my", "one", "all", "would", "there", "their", "what", "so", "up", "out", "if", "about", "who", "get", "which", "go", "me", "when", "make", "can", "like", "time", "no", "just", "him", "know", "take", "people", "into", "year", "your", "good", "some", "could", "them", "see", "other", "than", "then", "now", "look", "only", "come", "its", "over", "think", "also", "back", "after", "use", "two", "how", "our", "work", "first", "well", "way", "even", "new", "want", "because", "any", "these", "give", "day", "most", "us",
-	"life", "child", "world", "school", "state", "family", "student", "group", "country", "problem", "hand", "part", "place", "case", "week", "company", "system", "program", "question", "work", "government", "number", "night", "point", "home", "water", "room", "mother", "area", "money", "story", "fact", "month", "lot", "right", "study", "book", "eye", "job", "word", "business", "issue", "side", "kind", "head", "house", "service", "friend", "father", "power", "hour", "game", "line", "end", "member", "law", "car", "city", "community", "name", "president", "team", "minute", "idea", "kid", "body", "information", "back", "parent", "face", "others", "level", "office", "door", "health", "person", "art", "war", "history", "party", "result", "change", "morning", "reason", "research", "girl", "guy", "moment", "air", "teacher", "force", "education", "foot", "boy", "age", "policy", "everything", "process", "music", "market", "sense", "nation", "plan", "college", "interest", "death", "experience", "effect", "use", "class", "control", "care", "field", "development", "role", "effort", "rate", "heart", "drug", "show", "leader", "light", "voice", "wife", "police", "mind", "price", "report", "decision", "son", "view", "relationship", "town", "road", "arm", "difference", "value", "building", "action", "model", "season", "society", "tax", "director", "position", "player", "agree", "allow", "answer", "ask", "become", "begin", "believe", "borrow", "break", "bring", "build", "buy", "call", "carry", "catch", "choose", "close", "cut", "decide", "die", "draw", "drink", "drive", "eat", "explain", "fall", "feel", "fill", "find", "finish", "fly", "follow", "forget", "grow", "happen", "hear", "help", "hold", "hope", "keep", "kill", "learn", "leave", "let", "lie", "listen", "live", "lose", "love", "mean", "meet", "move", "need", "open", "pay", "play", "promise", "put", "reach", "read", "remember", "run", "sell", "send", "set", "sit", "speak", "spend", "stand", "start", "stop", "suggest", "talk", "teach", "tell", "think", "travel", "try", "turn", "understand", "wait", "walk", "watch", "win", "wonder", "work", "write", "worry",
+	"very", "thing", "man", "find", "here", "many", "those", "tell", "down", "may", "should", "call", "world", "still", "try", "last", "ask", "need", "too", "feel", "three", "never", "become", "between", "high", "really", "something", "another", "family", "own", "leave", "put", "old", "while", "mean", "keep", "student", "why", "let", "great", "same", "big", "group", "begin", "seem", "country", "help", "talk", "where", "turn", "problem", "every", "start", "hand", "might", "show", "part", "against", "place", "such", "again", "few", "case", "week", "company", "system", "each", "right", "program", "hear", "question", "during", "play", "government", "run", "small", "number", "off", "always", "move", "night", "live", "point", "believe", "hold", "today", "bring", "happen", "next", "without", "before", "large", "million", "must", "home", "under", "water", "room", "write", "mother", "area", "money", "story", "young", "fact", "month", "different", "lot", "study", "book", "eye", "job", "word", "though", "business", "issue", "side", "kind", "four", "head", "far", "black", "long", "both", "little", "house", "yes", "since", "provide", "service", "around", "friend", "important", "father", "sit", "away", "until", "power", "hour", "game", "often", "yet", "line", "end", "among", "ever", "stand", "bad", "lose", "however", "member", "pay", "law", "meet", "car", "city", "almost", "include", "continue", "set", "later", "community", "much", "name", "five", "once", "white", "least", "president", "learn", "real", "change", "team", "minute", "best", "several", "idea", "kid", "body", "information", "nothing", "ago", "lead", "social", "understand", "whether", "watch", "together", "follow", "parent", "stop", "face", "anything", "create", "public", "already", "speak", "others", "read", "level", "allow", "add", "office", "spend", "door", "health", "person", "art", "sure", "war", "history", "party", "within", "grow", "result", "open", "morning", "walk", "reason", "low", "win", "research", "girl", "guy", "early", "food", "moment", "air", "teacher", "force", "offer", "enough", "education", "across", "remember", "foot", "second", "boy", "maybe", "able", "age", "policy", "everything", "love", "process", "music", "buy", "probably", "wait", "market", "die", "send", "expect", "sense", "build", "stay", "fall", "nation", "plan", "cut", "college", "interest", "death", "course", "someone", "experience", "reach", "kill", "effect", "suggest", "class", "control", "care", "field", "pass", "sell", "development", "report", "role", "better", "effort", "decide", "rate", "strong", "heart", "drug", "leader", "light", "voice", "wife", "police", "mind", "price", "decision", "explain", "son", "hope", "view", "relationship", "carry", "town", "road", "drive", "arm", "break", "difference", "thank", "value", "building", "action", "model", "season", "society", "tax", "director", "position", "player", "agree", "eat", "close", "choose", "catch", "draw", "drink", "forget", "listen", "finish", "fly", "travel", "teach", "wonder", "worry", "fill", "promise", "borrow",
 }
 
 var pythonKeywords = []string{
-	"def", "class", "import", "return", "if", "else", "elif", "for", "while", "try", "except", "finally", "with", "as", "lambda", "yield", "True", "False", "None", "self", "print", "len", "range", "list", "dict", "set", "str", "int", "float", "__init__",
-	"super", "is", "in", "not", "and", "or", "break", "continue", "pass", "raise", "global", "nonlocal", "del", "from", "assert", "async", "await", "open", "read", "write", "close", "append", "extend", "pop", "remove", "sort", "reverse", "join", "split", "strip", "replace", "find", "count", "lower", "upper", "title", "keys", "values", "items", "get", "update", "clear", "copy", "map", "filter", "reduce", "zip", "enumerate", "sorted", "reversed", "min", "max", "sum", "abs", "round", "pow", "divmod", "all", "any", "isinstance", "issubclass", "hasattr", "getattr", "setattr", "delattr", "callable", "type", "id", "hash", "dir", "help", "vars", "locals", "globals", "input", "eval", "exec", "repr", "str", "bytes", "bytearray", "memoryview", "bool", "complex", "object", "property", "staticmethod", "classmethod", "importlib", "sys", "os", "math", "random", "datetime", "time", "json", "re", "collections", "itertools", "functools", "pathlib", "typing", "argparse", "logging", "subprocess", "threading", "multiprocessing", "asyncio", "socket", "requests", "numpy", "pandas", "matplotlib", "django", "flask", "sqlalchemy", "pytest", "unittest",
+	"self", "def", "return", "if", "in", "for", "None", "import", "from", "not", "else", "and", "is", "len", "print", "True", "False", "str", "class", "as", "with", "try", "except", "raise", "elif", "or", "range", "dict", "list", "int",
+	"while", "super", "__init__", "set", "float", "open", "append", "get", "items", "join", "split", "isinstance", "break", "continue", "pass", "lambda", "yield", "os", "sys", "re", "json", "type", "max", "min", "sum", "sorted", "enumerate", "zip", "map", "keys", "values", "update", "strip", "replace", "lower", "write", "read", "close", "pop", "extend", "copy", "sort", "count", "find", "upper", "remove", "getattr", "setattr", "hasattr", "all", "any", "abs", "round", "bool", "object", "property", "staticmethod", "classmethod", "async", "await", "finally", "assert", "del", "global", "nonlocal", "filter", "reversed", "bytes", "repr", "id", "hash", "callable", "input", "clear", "title", "reverse", "time", "datetime", "math", "random", "logging", "typing", "collections", "pathlib", "functools", "itertools", "subprocess", "argparse", "asyncio", "threading", "socket", "numpy", "pandas", "pytest", "unittest", "requests", "django", "flask", "sqlalchemy", "matplotlib", "multiprocessing", "importlib", "reduce", "pow", "divmod", "issubclass", "delattr", "dir", "help", "vars", "locals", "globals", "eval", "exec", "bytearray", "memoryview", "complex",
 }
 
 var cppKeywords = []string{
-	"int", "char", "float", "double", "bool", "void", "wchar_t", "class", "struct", "union", "enum", "template", "typename", "namespace", "using", "public", "private", "protected", "virtual", "friend", "this", "new", "delete", "operator", "true", "false", "nullptr", "if", "else", "for", "while", "do", "switch", "case", "default", "break", "continue", "return", "try", "catch", "throw", "const", "static", "volatile", "mutable", "extern", "register", "auto", "sizeof", "typedef", "std::cout", "std::cin", "std::vector", "std::string",
-	"std::map", "std::set", "std::list", "std::array", "std::pair", "std::tuple", "std::unique_ptr", "std::shared_ptr", "std::weak_ptr", "std::make_unique", "std::make_shared", "std::move", "std::forward", "std::function", "std::bind", "std::thread", "std::mutex", "std::lock_guard", "std::atomic", "std::condition_variable", "std::future", "std::promise", "std::chrono", "std::filesystem", "std::optional", "std::variant", "std::any", "std::string_view", "constexpr", "consteval", "constinit", "decltype", "noexcept", "override", "final", "explicit", "inline", "static_assert", "alignas", "alignof", "typeid", "dynamic_cast", "static_cast", "reinterpret_cast", "const_cast", "goto", "asm", "concept", "requires", "co_await", "co_yield", "co_return", "module", "import", "export", "#include", "#define", "#ifdef", "#ifndef", "#endif", "#pragma", "main", "argc", "argv", "std::endl", "push_back", "emplace_back", "pop_back", "begin", "end", "cbegin", "cend", "rbegin", "rend", "size", "empty", "clear", "resize", "reserve", "find", "count", "insert", "erase", "swap", "sort",
+	"int", "return", "const", "if", "std::string", "auto", "void", "for", "std::vector", "bool", "else", "this", "char", "class", "struct", "nullptr", "while", "new", "delete", "true", "false", "std::cout", "std::endl", "double",
+	"size", "std::map", "begin", "end", "push_back", "static", "case", "break", "public", "private", "template", "typename", "namespace", "using", "std::move", "virtual", "override", "explicit", "inline", "constexpr", "static_cast", "std::unique_ptr", "std::shared_ptr", "std::make_unique", "std::make_shared", "float", "enum", "switch", "default", "protected", "operator", "#include", "#define", "#ifndef", "#endif", "#ifdef", "#pragma", "empty", "find", "insert", "erase", "clear", "emplace_back", "resize", "reserve", "count", "std::pair", "std::function", "std::set", "std::array", "std::optional", "std::string_view", "std::mutex", "std::lock_guard", "std::thread", "std::atomic", "std::chrono", "std::forward", "std::cin", "std::tuple", "std::list", "std::weak_ptr", "std::variant", "std::filesystem", "std::bind", "std::future", "std::promise", "std::condition_variable", "std::any", "try", "catch", "throw", "noexcept", "final", "do", "continue", "sizeof", "typedef", "union", "friend", "extern", "mutable", "volatile", "decltype", "dynamic_cast", "reinterpret_cast", "const_cast", "static_assert", "typeid", "main", "argc", "argv", "pop_back", "cbegin", "cend", "rbegin", "rend", "swap", "sort", "wchar_t", "alignas", "alignof", "register", "goto", "asm", "concept", "requires", "consteval", "constinit", "co_await", "co_yield", "co_return", "module", "import", "export",
 }
 
 var jsKeywords = []string{
-	"var", "let", "const", "function", "return", "if", "else", "for", "while", "do", "switch", "case", "default", "break", "continue", "try", "catch", "finally", "throw", "new", "this", "super", "class", "extends", "import", "export", "default", "async", "await", "yield", "void", "typeof", "instanceof", "in", "of", "delete", "true", "false", "null", "undefined", "NaN", "Infinity", "console.log", "document", "window", "Promise", "JSON",
-	"map", "filter", "reduce", "forEach", "find", "findIndex", "includes", "indexOf", "join", "split", "slice", "splice", "push", "pop", "shift", "unshift", "concat", "sort", "reverse", "some", "every", "fill", "from", "isArray", "keys", "values", "entries", "assign", "create", "defineProperty", "freeze", "seal", "toString", "parseInt", "parseFloat", "setTimeout", "setInterval", "clearTimeout", "clearInterval", "addEventListener", "removeEventListener", "getElementById", "querySelector", "querySelectorAll", "createElement", "appendChild", "removeChild", "classList", "setAttribute", "getAttribute", "innerHTML", "innerText", "textContent", "fetch", "then", "catch", "resolve", "reject", "all", "race", "any", "allSettled", "Math", "Date", "RegExp", "Error", "Map", "Set", "WeakMap", "WeakSet", "Symbol", "BigInt", "arrow", "callback", "closure", "prototype", "constructor", "module", "require", "exports",
+	"const", "this", "return", "function", "if", "let", "import", "export", "from", "else", "for", "new", "true", "false", "null", "undefined", "await", "async", "of", "in", "class", "console.log", "map", "then",
+	"push", "filter", "forEach", "catch", "try", "throw", "Error", "typeof", "Promise", "JSON", "var", "resolve", "reject", "require", "module", "exports", "keys", "join", "split", "slice", "includes", "find", "indexOf", "reduce", "Math", "Date", "setTimeout", "document", "window", "switch", "case", "break", "default", "continue", "while", "super", "extends", "constructor", "instanceof", "delete", "void", "yield", "do", "finally", "assign", "values", "entries", "concat", "some", "every", "sort", "splice", "pop", "shift", "unshift", "reverse", "fill", "findIndex", "isArray", "toString", "parseInt", "parseFloat", "Map", "Set", "RegExp", "Symbol", "addEventListener", "removeEventListener", "querySelector", "querySelectorAll", "getElementById", "createElement", "appendChild", "removeChild", "classList", "setAttribute", "getAttribute", "innerHTML", "textContent", "innerText", "fetch", "all", "race", "allSettled", "any", "setInterval", "clearTimeout", "clearInterval", "create", "defineProperty", "freeze", "seal", "prototype", "NaN", "Infinity", "WeakMap", "WeakSet", "BigInt",
 }
 
 var rustKeywords = []string{
-	"fn", "let", "mut", "const", "static", "if", "else", "match", "loop", "while", "for", "in", "return", "break", "continue", "struct", "enum", "trait", "impl", "use", "mod", "pub", "crate", "super", "self", "Self", "type", "where", "unsafe", "extern", "async", "await", "move", "ref", "box", "true", "false", "None", "Some", "Ok", "Err", "String", "Vec", "Option", "Result", "println!", "format!", "vec!", "pub",
-	"panic!", "assert!", "assert_eq!", "dbg!", "todo!", "unreachable!", "macro_rules!", "derive", "Debug", "Clone", "Copy", "PartialEq", "Eq", "PartialOrd", "Ord", "Hash", "Default", "Display", "Error", "From", "Into", "AsRef", "AsMut", "Drop", "Send", "Sync", "Box", "Rc", "Arc", "Cell", "RefCell", "Mutex", "RwLock", "HashMap", "HashSet", "BTreeMap", "BTreeSet", "LinkedList", "BinaryHeap", "Duration", "Instant", "Thread", "File", "Path", "PathBuf", "io", "fs", "env", "args", "Result", "Option", "u8", "u16", "u32", "u64", "u128", "usize", "i8", "i16", "i32", "i64", "i128", "isize", "f32", "f64", "bool", "char", "str", "slice", "iter", "collect", "map", "filter", "fold", "zip", "enumerate", "unwrap", "expect", "unwrap_or", "unwrap_or_else", "and_then", "ok_or", "as_str", "as_bytes", "to_string", "to_owned", "push", "pop", "insert", "remove", "contains", "len", "is_empty",
+	"let", "fn", "self", "mut", "pub", "use", "impl", "match", "if", "Some", "None", "Ok", "Err", "return", "String", "Vec", "for", "in", "struct", "Self", "unwrap", "new", "usize", "u8", "i32", "as",
+	"Option", "Result", "println!", "format!", "vec!", "const", "else", "enum", "trait", "mod", "crate", "super", "type", "where", "iter", "map", "collect", "to_string", "len", "push", "Box", "Debug", "Clone", "derive", "true", "false", "bool", "u32", "u64", "i64", "f64", "str", "char", "HashMap", "Arc", "Mutex", "while", "loop", "break", "continue", "move", "ref", "async", "await", "static", "unsafe", "extern", "expect", "unwrap_or", "is_empty", "contains", "as_str", "to_owned", "and_then", "ok_or", "unwrap_or_else", "filter", "enumerate", "zip", "fold", "insert", "remove", "pop", "as_bytes", "panic!", "assert!", "assert_eq!", "dbg!", "todo!", "unreachable!", "macro_rules!", "Copy", "PartialEq", "Eq", "Default", "Display", "Error", "From", "Into", "Hash", "PartialOrd", "Ord", "AsRef", "AsMut", "Drop", "Send", "Sync", "Rc", "RefCell", "Cell", "RwLock", "HashSet", "BTreeMap", "BTreeSet", "BinaryHeap", "LinkedList", "Path", "PathBuf", "File", "io", "fs", "env", "args", "Duration", "Instant", "Thread", "u16", "i8", "i16", "u128", "i128", "isize", "f32", "slice", "box",
 }
 
 var goKeywords = []string{
-	"func", "package", "import", "return", "if", "else", "for", "range",
-	"struct", "interface", "type", "var", "const", "go", "defer", "map",
-	"chan", "select", "case", "switch", "break", "continue", "fallthrough",
-	"goto", "true", "false", "nil", "error", "string", "int", "bool",
-	"byte", "rune", "float32", "float64", "complex64", "complex128", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "int8", "int16", "int32", "int64", "make", "new", "len", "cap", "append", "copy", "close", "delete", "complex", "real", "imag", "panic", "recover", "print", "println", "fmt", "os", "io", "bufio", "net", "http", "json", "xml", "sql", "time", "log", "sync", "atomic", "context", "errors", "flag", "path", "filepath", "sort", "strings", "strconv", "unicode", "math", "rand", "crypto", "sha256", "aes", "bytes", "reflect", "unsafe", "syscall", "exec", "signal", "user", "template", "html", "image", "png", "jpeg", "gif", "testing", "pprof", "runtime", "debug", "main", "init", "Error", "String", "Write", "Read", "Close", "ServeHTTP", "Handler", "Context", "Done", "Err", "Value", "Type", "Kind", "Field", "Method", "Add", "Done", "Wait", "Lock", "Unlock", "RLock", "RUnlock", "Once", "Pool", "Map", "Cond", "WaitGroup", "Mutex", "RWMutex",
+	"err", "if", "return", "nil", "func", "string", "int", "error", "fmt", "range", "for", "len", "var", "type", "struct", "else", "make", "append", "true", "false", "package", "import", "case", "bool", "byte", "map", "const", "interface", "defer", "go", "switch", "context", "time", "strings", "errors", "new", "break", "continue", "select", "chan", "default",
+	"os", "io", "json", "http", "sync", "log", "sort", "strconv", "bytes", "Error", "String", "Context", "Err", "Done", "rune", "int64", "float64", "uint64", "int32", "uint8", "uint32", "close", "copy", "delete", "panic", "cap", "filepath", "path", "bufio", "math", "testing", "main", "init", "Read", "Write", "Close", "Lock", "Unlock", "Mutex", "WaitGroup", "Add", "Wait", "Value", "Handler", "ServeHTTP", "sql", "net", "reflect", "Type", "Kind", "Field", "Method", "atomic", "unicode", "flag", "runtime", "exec", "signal", "rand", "crypto", "sha256", "aes", "xml", "template", "html", "image", "png", "jpeg", "gif", "pprof", "debug", "user", "syscall", "unsafe", "RWMutex", "RLock", "RUnlock", "Once", "Pool", "Map", "Cond", "recover", "print", "println", "float32", "int8", "int16", "uint", "uint16", "uintptr", "complex", "real", "imag", "complex64", "complex128", "goto", "fallthrough",
 }
 
 var goSnippets = []string{
@@ -262,81 +310,21 @@ var pythonSymbols = []string{
 }
 
 func (g *Generator) GenerateLesson(lessonType LessonType, length int) string {
+	p := g.pack()
 	switch lessonType {
 	case TypeBigrams:
-		switch g.Language {
-		case LangSpanish:
-			return g.generateFromList(bigramsSpanish, length, " ")
-		case LangFrench:
-			return g.generateFromList(bigramsFrench, length, " ")
-		case LangGerman:
-			return g.generateFromList(bigramsGerman, length, " ")
-		case LangGo, LangPython, LangCpp, LangJavascript, LangRust:
-			return g.generateFromList(bigramsCode, length, " ")
-		default: // English
-			return g.generateFromList(bigramsEnglish, length, " ")
-		}
+		return g.GeneratePseudoWords(length)
 	case TypeWords:
-		switch g.Language {
-		case LangSpanish:
-			return g.generateFromList(spanishWords, length, " ")
-		case LangEnglish:
-			return g.generateFromList(englishWords, length, " ")
-		case LangFrench:
-			return g.generateFromList(frenchWords, length, " ")
-		case LangGerman:
-			return g.generateFromList(germanWords, length, " ")
-		case LangPython:
-			return g.generateFromList(pythonKeywords, length, " ")
-		case LangCpp:
-			return g.generateFromList(cppKeywords, length, " ")
-		case LangJavascript:
-			return g.generateFromList(jsKeywords, length, " ")
-		case LangRust:
-			return g.generateFromList(rustKeywords, length, " ")
-		default: // Go
-			return g.generateFromList(goKeywords, length, " ")
-		}
+		return g.generateFromList(g.vocabulary(p.Words, p.Weights), p.Weights, true, length, " ")
 	case TypeSymbols:
-		switch g.Language {
-		case LangPython:
-			return g.generateFromList(pythonSymbols, length, " ")
-		case LangCpp:
-			return g.generateFromList(cppSymbols, length, " ")
-		case LangJavascript:
-			return g.generateFromList(jsSymbols, length, " ")
-		case LangRust:
-			return g.generateFromList(rustSymbols, length, " ")
-		default:
-			return g.generateFromList(goSymbols, length, " ")
-		}
+		return g.generateFromList(p.symbols(), p.Weights, false, length, " ")
 	case TypeCode:
-		switch g.Language {
-		case LangPython:
-			return g.generateCode(pythonSnippets, length)
-		case LangCpp:
-			return g.generateCode(cppSnippets, length)
-		case LangJavascript:
-			return g.generateCode(jsSnippets, length)
-		case LangRust:
-			return g.generateCode(rustSnippets, length)
-		case LangSpanish, LangEnglish, LangFrench, LangGerman:
-			var pool []string
-			if g.Language == LangSpanish {
-				pool = spanishWords
-			} else if g.Language == LangFrench {
-				pool = frenchWords
-			} else if g.Language == LangGerman {
-				pool = germanWords
-			} else {
-				pool = englishWords
-			}
-			return g.generateFromList(pool, length*2, " ")
-		default: // Go
-			return g.generateCode(goSnippets, length)
+		if len(p.Snippets) == 0 {
+			return g.generateFromList(g.vocabulary(p.Words, p.Weights), p.Weights, true, length*2, " ")
 		}
+		return g.generateCode(p.Snippets, length)
 	default:
-		return g.generateFromList(goKeywords, length, " ")
+		return g.generateFromList(p.Words, p.Weights, true, length, " ")
 	}
 }
 
@@ -348,10 +336,75 @@ func (g *Generator) GenerateFromFile(filepath string) (string, error) {
 	return string(content), nil
 }
 
-func (g *Generator) generateFromList(list []string, count int, sep string) string {
+// vocabulary trims a word list to the TopWords most frequent words.
+func (g *Generator) vocabulary(list []string, weights map[string]float64) []string {
+	list = rank(list, weights)
+	if g.TopWords > 0 && g.TopWords < len(list) {
+		return list[:g.TopWords]
+	}
+	return list
+}
+
+// rank drops repeated items and, given weights, sorts the rest from the
+// most frequent. Without weights the list's own order is the ranking.
+func rank(list []string, weights map[string]float64) []string {
+	list = unique(list)
+	if len(weights) > 0 {
+		sort.SliceStable(list, func(i, j int) bool { return weight(list[i], weights) > weight(list[j], weights) })
+	}
+	return list
+}
+
+// weight is an item's usage frequency; items a pack gives no weight are
+// taken to be rare.
+func weight(item string, weights map[string]float64) float64 {
+	if w, ok := weights[item]; ok {
+		return w
+	}
+	return 1
+}
+
+// unique drops repeated items, keeping the first (most frequent) one.
+func unique(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// ZipfExponent shapes how quickly usage falls off with frequency rank:
+// the item at rank r (from 1) is drawn with weight 1/r^ZipfExponent.
+const ZipfExponent = 1.0
+
+// generateFromList draws count items from list in proportion to their
+// weights. Without weights, a ranked list, ordered from the most to the
+// least frequent item, gets Zipf weights by rank so that common words come
+// up far more often than rare ones; any other list is drawn uniformly.
+func (g *Generator) generateFromList(list []string, weights map[string]float64, ranked bool, count int, sep string) string {
+	list = unique(list)
+	cumulative := make([]float64, len(list))
+	var total float64
+	for i, item := range list {
+		switch {
+		case len(weights) > 0:
+			total += weight(item, weights)
+		case ranked:
+			total += 1 / math.Pow(float64(i+1), ZipfExponent)
+		default:
+			total++
+		}
+		cumulative[i] = total
+	}
+
 	var result []string
 	for i := 0; i < count; i++ {
-		result = append(result, list[g.rand.Intn(len(list))])
+		x := g.rand.Float64() * total
+		result = append(result, list[sort.SearchFloat64s(cumulative, x)])
 	}
 	return strings.Join(result, sep)
 }
@@ -369,26 +422,10 @@ func (g *Generator) GenerateWeaknessLesson(weakKeys []WeakKey, length int) strin
 		return g.GenerateLesson(TypeWords, length)
 	}
 
-	var sourcePool []string
-	switch g.Language {
-	case LangSpanish:
-		sourcePool = spanishWords
-	case LangEnglish:
-		sourcePool = englishWords
-	case LangFrench:
-		sourcePool = frenchWords
-	case LangGerman:
-		sourcePool = germanWords
-	case LangPython:
-		sourcePool = append(pythonKeywords, pythonSymbols...)
-	case LangCpp:
-		sourcePool = append(cppKeywords, cppSymbols...)
-	case LangJavascript:
-		sourcePool = append(jsKeywords, jsSymbols...)
-	case LangRust:
-		sourcePool = append(rustKeywords, rustSymbols...)
-	default: // Go
-		sourcePool = append(goKeywords, goSymbols...)
+	p := g.pack()
+	sourcePool := p.Words
+	if !p.Natural {
+		sourcePool = append(append([]string(nil), p.Words...), p.symbols()...)
 	}
 
 	var wordPool []string
@@ -402,21 +439,22 @@ func (g *Generator) GenerateWeaknessLesson(weakKeys []WeakKey, length int) strin
 			}
 		}
 
-		var bigramPool []string
-		switch g.Language {
-		case LangSpanish:
-			bigramPool = bigramsSpanish
-		case LangFrench:
-			bigramPool = bigramsFrench
-		case LangGerman:
-			bigramPool = bigramsGerman
-		case LangEnglish:
-			bigramPool = bigramsEnglish
-		default:
-			bigramPool = bigramsCode
+		if weak.ConfusedWith != "" {
+			for _, word := range sourcePool {
+				if strings.Contains(word, weak.ConfusedWith) && !seen[word] {
+					wordPool = append(wordPool, word)
+					seen[word] = true
+				}
+			}
+			for _, drill := range contrastDrill(weak.Key, weak.ConfusedWith) {
+				if !seen[drill] {
+					wordPool = append(wordPool, drill)
+					seen[drill] = true
+				}
+			}
 		}
 
-		for _, bigram := range bigramPool {
+		for _, bigram := range p.bigrams() {
 			if strings.Contains(bigram, weak.Key) && !seen[bigram] {
 				wordPool = append(wordPool, bigram)
 				seen[bigram] = true
@@ -435,3 +473,17 @@ func (g *Generator) GenerateWeaknessLesson(weakKeys []WeakKey, length int) strin
 
 	return strings.Join(result, " ")
 }
+
+// contrastDrill alternates two confused keys so the difference has to be
+// made on every stroke, e.g. "{[", "[{", "{[{", "[{[".
+func contrastDrill(key, confused string) []string {
+	if strings.TrimSpace(key) == "" || strings.TrimSpace(confused) == "" {
+		return nil
+	}
+	return []string{
+		key + confused,
+		confused + key,
+		key + confused + key,
+		confused + key + confused,
+	}
+}
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"math/rand"
 	"strings"
 	"testing"
 )
@@ -45,3 +46,118 @@ func TestWeaknessLessonFallback(t *testing.T) {
 		t.Errorf("Expected 5 words in fallback, got %d", len(words))
 	}
 }
+
+func TestLessonTypeNames(t *testing.T) {
+	for _, lt := range []LessonType{TypeBigrams, TypeWords, TypeSymbols, TypeCode, TypeFile, TypeWeaknesses, TypeKeys, TypeRepo} {
+		parsed, ok := ParseLessonType(lt.String())
+		if !ok || parsed != lt {
+			t.Errorf("Round trip failed for %s", lt)
+		}
+	}
+
+	if _, ok := ParseLessonType("nonsense"); ok {
+		t.Error("Expected unknown lesson type to fail parsing")
+	}
+}
+
+func TestWeaknessLessonContrastDrill(t *testing.T) {
+	g := New()
+	g.SetLanguage(LangGo)
+
+	drills := contrastDrill("{", "[")
+	if len(drills) != 4 || drills[0] != "{[" || drills[3] != "[{[" {
+		t.Errorf("Unexpected contrast drill: %v", drills)
+	}
+	if contrastDrill(" ", "x") != nil {
+		t.Error("Expected no contrast drill for whitespace")
+	}
+
+	lesson := g.GenerateWeaknessLesson([]WeakKey{{Key: "{", ErrorRate: 0.4, ConfusedWith: "["}}, 200)
+	for _, drill := range drills {
+		if strings.Contains(" "+lesson+" ", " "+drill+" ") {
+			return
+		}
+	}
+	t.Errorf("Expected contrast drills in a 200-word lesson, got %q", lesson)
+}
+
+func TestGenerateFromKeys(t *testing.T) {
+	g := New()
+	g.SetLanguage(LangEnglish)
+
+	keys := []string{"a", "s", "d", "f", "j", "k", "l", "e"}
+	lesson := g.GenerateFromKeys(keys, "e", 20)
+
+	words := strings.Split(lesson, " ")
+	if len(words) != 20 {
+		t.Fatalf("Expected 20 words, got %d: %q", len(words), lesson)
+	}
+	for _, r := range lesson {
+		if r != ' ' && !strings.ContainsRune("asdfjkle", r) {
+			t.Fatalf("Lesson uses a locked key %q: %q", r, lesson)
+		}
+	}
+	for i := 0; i < len(words); i += 2 {
+		if !strings.Contains(words[i], "e") {
+			t.Errorf("Expected word %d to drill the new key, got %q", i, words[i])
+		}
+	}
+}
+
+func TestLetterFrequency(t *testing.T) {
+	if got := LetterFrequency(LangGo); got != LetterFrequency(LangEnglish) {
+		t.Errorf("Expected programming languages to use English letters, got %q", got)
+	}
+	if !strings.ContainsRune(LetterFrequency(LangSpanish), 'ñ') {
+		t.Error("Expected Spanish letters to include ñ")
+	}
+}
+
+func TestWordFrequency(t *testing.T) {
+	g := New()
+	g.rand = rand.New(rand.NewSource(1))
+
+	counts := make(map[string]int)
+	for _, word := range strings.Fields(g.GenerateLesson(TypeWords, 2000)) {
+		counts[word]++
+	}
+	if counts["err"] < 10*counts["fallthrough"] {
+		t.Errorf("Expected err far more often than fallthrough, got %d and %d", counts["err"], counts["fallthrough"])
+	}
+
+	g.TopWords = 5
+	g.SetLanguage(LangEnglish)
+	for _, word := range strings.Fields(g.GenerateLesson(TypeWords, 200)) {
+		if !strings.Contains(" the be to of and ", " "+word+" ") {
+			t.Fatalf("Expected only the 5 most common words, got %q", word)
+		}
+	}
+}
+
+func TestSymbolsUniform(t *testing.T) {
+	g := New()
+	g.rand = rand.New(rand.NewSource(1))
+
+	counts := make(map[string]int)
+	for _, symbol := range strings.Fields(g.GenerateLesson(TypeSymbols, 4000)) {
+		counts[symbol]++
+	}
+	first, last := goSymbols[0], goSymbols[len(goSymbols)-1]
+	if counts[first] > 2*counts[last] {
+		t.Errorf("Expected symbols drawn evenly, got %q %d times and %q %d times", first, counts[first], last, counts[last])
+	}
+}
+
+func TestBuiltinListsUnique(t *testing.T) {
+	for _, p := range builtinPacks {
+		for _, list := range [][]string{p.Words, p.Symbols} {
+			seen := make(map[string]bool)
+			for _, item := range list {
+				if seen[item] {
+					t.Errorf("%s lists %q twice", p.Name, item)
+				}
+				seen[item] = true
+			}
+		}
+	}
+}
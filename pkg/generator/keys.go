@@ -0,0 +1,122 @@
+package generator
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// LetterFrequency returns the letters of the language, most common first;
+// keys are unlocked in this order. Programming languages use English,
+// whose words fill their key lessons.
+func LetterFrequency(lang Language) string {
+	if p, ok := packs[lang]; ok && p.Natural {
+		return p.letters()
+	}
+	return packs[LangEnglish].letters()
+}
+
+func (g *Generator) naturalWords() []string {
+	if p := g.pack(); p.Natural {
+		return p.Words
+	}
+	return englishWords
+}
+
+func (g *Generator) naturalBigrams() []string {
+	if p := g.pack(); p.Natural {
+		return p.bigrams()
+	}
+	return bigramsEnglish
+}
+
+// minRealWords is how many distinct real words the unlocked keys must
+// spell before a lesson stops mixing in pseudo-words.
+const minRealWords = 10
+
+// GenerateFromKeys builds a lesson of count words typed with the given
+// keys only: real words where the keys spell enough of them, pseudo-words
+// otherwise. Every other word contains focus, the newest key, if set.
+func (g *Generator) GenerateFromKeys(keys []string, focus string, count int) string {
+	allowed := make(map[rune]bool)
+	var letters []rune
+	for _, k := range keys {
+		for _, r := range k {
+			if !allowed[r] {
+				allowed[r] = true
+				letters = append(letters, r)
+			}
+		}
+	}
+	if len(letters) == 0 {
+		return ""
+	}
+
+	var real, focused []string
+	seen := make(map[string]bool)
+	for _, word := range g.naturalWords() {
+		word = strings.ToLower(word)
+		if seen[word] || !spelledWith(word, allowed) {
+			continue
+		}
+		seen[word] = true
+		real = append(real, word)
+		if focus != "" && strings.Contains(word, focus) {
+			focused = append(focused, word)
+		}
+	}
+
+	// Transitions between unlocked letters make pseudo-words pronounceable.
+	next := make(map[rune][]rune)
+	for _, bigram := range g.naturalBigrams() {
+		a, size := utf8.DecodeRuneInString(bigram)
+		b, _ := utf8.DecodeRuneInString(bigram[size:])
+		if allowed[a] && allowed[b] {
+			next[a] = append(next[a], b)
+		}
+	}
+
+	words := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		wantFocus := focus != "" && i%2 == 0
+		switch {
+		case wantFocus && len(focused) > 0 && g.rand.Intn(minRealWords) < len(focused):
+			words = append(words, focused[g.rand.Intn(len(focused))])
+		case wantFocus:
+			words = append(words, g.pseudoWord(letters, next, focus))
+		case g.rand.Intn(minRealWords) < len(real):
+			words = append(words, real[g.rand.Intn(len(real))])
+		default:
+			words = append(words, g.pseudoWord(letters, next, ""))
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+func spelledWith(word string, allowed map[rune]bool) bool {
+	for _, r := range word {
+		if !allowed[r] {
+			return false
+		}
+	}
+	return word != ""
+}
+
+// pseudoWord strings three to six letters together, following common
+// transitions where it can. A non-empty focus is placed somewhere in it.
+func (g *Generator) pseudoWord(letters []rune, next map[rune][]rune, focus string) string {
+	n := 3 + g.rand.Intn(4)
+	word := []rune{letters[g.rand.Intn(len(letters))]}
+	for len(word) < n {
+		if options := next[word[len(word)-1]]; len(options) > 0 && g.rand.Intn(4) > 0 {
+			word = append(word, options[g.rand.Intn(len(options))])
+		} else {
+			word = append(word, letters[g.rand.Intn(len(letters))])
+		}
+	}
+
+	if focus != "" && !strings.ContainsRune(string(word), []rune(focus)[0]) {
+		word[g.rand.Intn(len(word))] = []rune(focus)[0]
+	}
+	return string(word)
+}
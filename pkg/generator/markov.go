@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Word boundaries in a Markov context. Neither can appear in a word.
+const (
+	wordStart = '^'
+	wordEnd   = '$'
+)
+
+// Markov is a character n-gram model of words: it learns which letter
+// follows each run of order letters, and strings letters together the
+// same way to make pronounceable pseudo-words.
+type Markov struct {
+	order  int
+	counts map[string]map[rune]float64
+}
+
+func NewMarkov(order int) *Markov {
+	if order < 1 {
+		order = 1
+	}
+	return &Markov{order: order, counts: make(map[string]map[rune]float64)}
+}
+
+// Train adds words to the model. Words with anything but letters are
+// skipped; case is ignored.
+func (m *Markov) Train(words ...string) {
+	for _, word := range words {
+		word = strings.ToLower(word)
+		if word == "" || strings.IndexFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+			continue
+		}
+
+		context := []rune(strings.Repeat(string(wordStart), m.order))
+		for _, r := range append([]rune(word), wordEnd) {
+			key := string(context)
+			if m.counts[key] == nil {
+				m.counts[key] = make(map[rune]float64)
+			}
+			m.counts[key][r]++
+			context = append(context[1:], r)
+		}
+	}
+}
+
+// TrainText trains on every word of free text, such as a book or a
+// source file.
+func (m *Markov) TrainText(text string) {
+	m.Train(strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) })...)
+}
+
+// Empty reports whether the model has learned nothing yet.
+func (m *Markov) Empty() bool {
+	return len(m.counts) == 0
+}
+
+// Word generates a pseudo-word of minLen to maxLen letters. Each
+// transition's likelihood is multiplied by 1 + bias×weak[bigram], so a
+// positive bias steers words through the weak bigrams (weighted 0 to 1).
+func (m *Markov) Word(rng *rand.Rand, minLen, maxLen int, weak map[string]float64, bias float64) string {
+	if m.Empty() {
+		return ""
+	}
+
+	context := []rune(strings.Repeat(string(wordStart), m.order))
+	var word []rune
+	for len(word) < maxLen {
+		options := m.counts[string(context)]
+
+		var total float64
+		weights := make(map[rune]float64, len(options))
+		for r, count := range options {
+			if r == wordEnd && len(word) < minLen {
+				continue
+			}
+			w := count
+			if len(word) > 0 && r != wordEnd {
+				w *= 1 + bias*weak[string([]rune{word[len(word)-1], r})]
+			}
+			weights[r] = w
+			total += w
+		}
+		if total == 0 {
+			break
+		}
+
+		r := pick(rng, weights, total)
+		if r == wordEnd {
+			break
+		}
+		word = append(word, r)
+		context = append(context[1:], r)
+	}
+
+	return string(word)
+}
+
+// pick draws a key with probability proportional to its weight. Keys are
+// visited in sorted order so a seeded rng gives repeatable words.
+func pick(rng *rand.Rand, weights map[rune]float64, total float64) rune {
+	keys := make([]rune, 0, len(weights))
+	for r := range weights {
+		keys = append(keys, r)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	x := rng.Float64() * total
+	for _, r := range keys {
+		x -= weights[r]
+		if x < 0 {
+			return r
+		}
+	}
+	return keys[len(keys)-1]
+}
+
+// MarkovOrder is the context length of the generator's language models.
+const MarkovOrder = 2
+
+// DefaultWeakBias is how strongly pseudo-words favour weak bigrams.
+const DefaultWeakBias = 2.0
+
+// SetCorpus trains the pseudo-word model on the user's own text instead
+// of the built-in word lists. Empty text goes back to the word lists.
+func (g *Generator) SetCorpus(text string) {
+	g.corpus = nil
+	if text == "" {
+		return
+	}
+	m := NewMarkov(MarkovOrder)
+	m.TrainText(text)
+	if !m.Empty() {
+		g.corpus = m
+	}
+}
+
+// SetWeakBigrams sets the bigrams pseudo-words should favour, by weight
+// from 0 to 1, and how strongly (0 disables the bias).
+func (g *Generator) SetWeakBigrams(weak map[string]float64, bias float64) {
+	g.weakBigrams = weak
+	g.WeakBias = bias
+}
+
+func (g *Generator) markovModel() *Markov {
+	if g.corpus != nil {
+		return g.corpus
+	}
+	if m, ok := g.models[g.Language]; ok {
+		return m
+	}
+
+	m := NewMarkov(MarkovOrder)
+	m.Train(g.naturalWords()...)
+	if p := g.pack(); !p.Natural {
+		m.Train(p.Words...)
+	}
+
+	if g.models == nil {
+		g.models = make(map[Language]*Markov)
+	}
+	g.models[g.Language] = m
+	return m
+}
+
+// GeneratePseudoWords returns count pronounceable pseudo-words in the
+// current language, biased towards the weak bigrams.
+func (g *Generator) GeneratePseudoWords(count int) string {
+	m := g.markovModel()
+	words := make([]string, 0, count)
+	for len(words) < count {
+		words = append(words, m.Word(g.rand, 2, 7, g.weakBigrams, g.WeakBias))
+	}
+	return strings.Join(words, " ")
+}
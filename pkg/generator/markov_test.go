@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestMarkovWords(t *testing.T) {
+	m := NewMarkov(2)
+	m.Train("banana", "bandana", "Cabana", "std::cout")
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		word := m.Word(rng, 2, 8, nil, 0)
+		if n := len([]rune(word)); n < 2 || n > 8 {
+			t.Fatalf("Expected 2 to 8 letters, got %q", word)
+		}
+		for _, r := range word {
+			if !strings.ContainsRune("abncd", r) {
+				t.Fatalf("Word %q uses a letter the model never saw", word)
+			}
+		}
+	}
+}
+
+func TestMarkovTrainText(t *testing.T) {
+	m := NewMarkov(2)
+	if !m.Empty() {
+		t.Fatal("Expected a new model to be empty")
+	}
+
+	m.TrainText("func main() { fmt.Println(\"hi\") }")
+	if m.Empty() {
+		t.Fatal("Expected the model to learn from text")
+	}
+	if word := m.Word(rand.New(rand.NewSource(1)), 1, 10, nil, 0); strings.ContainsAny(word, "(){}.\"") {
+		t.Errorf("Expected letters only, got %q", word)
+	}
+}
+
+func TestMarkovWeakBias(t *testing.T) {
+	m := NewMarkov(1)
+	m.Train("ab", "ac")
+
+	count := func(bias float64) int {
+		rng := rand.New(rand.NewSource(1))
+		n := 0
+		for i := 0; i < 200; i++ {
+			if m.Word(rng, 2, 2, map[string]float64{"ab": 1}, bias) == "ab" {
+				n++
+			}
+		}
+		return n
+	}
+
+	plain, biased := count(0), count(10)
+	if plain < 60 || plain > 140 {
+		t.Errorf("Expected ab about half the time without bias, got %d/200", plain)
+	}
+	if biased < 170 {
+		t.Errorf("Expected a strong bias towards ab, got %d/200", biased)
+	}
+}
+
+func TestBigramLessonsArePseudoWords(t *testing.T) {
+	g := New()
+	for _, lang := range []Language{LangEnglish, LangSpanish, LangGo} {
+		g.SetLanguage(lang)
+		lesson := g.GenerateLesson(TypeBigrams, 10)
+
+		words := strings.Split(lesson, " ")
+		if len(words) != 10 {
+			t.Errorf("Language %s: expected 10 words, got %d", lang, len(words))
+		}
+		if strings.IndexFunc(lesson, func(r rune) bool { return r != ' ' && !unicode.IsLetter(r) }) >= 0 {
+			t.Errorf("Language %s: expected letters only, got %q", lang, lesson)
+		}
+	}
+
+	g.SetCorpus("zzyzx zyzzyva")
+	for _, r := range g.GenerateLesson(TypeBigrams, 10) {
+		if r != ' ' && !strings.ContainsRune("zyxva", r) {
+			t.Fatalf("Expected the corpus to replace the word lists, got %q", r)
+		}
+	}
+}
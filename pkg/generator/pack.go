@@ -0,0 +1,228 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"kata/assets"
+)
+
+// Pack is everything lessons in one language are generated from. Packs
+// are written in YAML or JSON, e.g.
+//
+//	name: kotlin
+//	words: [val, fun, return, if, ...]   # most frequent first
+//	symbols: ["{}", "()", "->", ...]
+//	snippets:
+//	  - "fun main() {\n    println(\"Hi\")\n}"
+//	weights: {val: 120, fun: 95}          # optional usage counts
+//
+// Lists a pack leaves out are borrowed from Go, except snippets: without
+// them, code lessons are longer word lessons.
+type Pack struct {
+	Name Language `yaml:"name" json:"name"`
+	// Natural marks a spoken language rather than a programming one.
+	Natural  bool     `yaml:"natural,omitempty" json:"natural,omitempty"`
+	Bigrams  []string `yaml:"bigrams,omitempty" json:"bigrams,omitempty"`
+	Words    []string `yaml:"words" json:"words"`
+	Symbols  []string `yaml:"symbols,omitempty" json:"symbols,omitempty"`
+	Snippets []string `yaml:"snippets,omitempty" json:"snippets,omitempty"`
+	// Weights are the usage frequencies of words and symbols. Without
+	// them, lists are taken to be ordered from the most frequent item.
+	Weights map[string]float64 `yaml:"weights,omitempty" json:"weights,omitempty"`
+	// Letters orders a natural language's letters from the most common,
+	// for key unlocking. Left out, it is counted from the words.
+	Letters string `yaml:"letters,omitempty" json:"letters,omitempty"`
+
+	// Source is the file the pack was loaded from; empty for built-ins.
+	Source string `yaml:"-" json:"-"`
+}
+
+var builtinPacks = []Pack{
+	{Name: LangGo, Bigrams: bigramsCode, Words: goKeywords, Symbols: goSymbols, Snippets: goSnippets},
+	{Name: LangCpp, Bigrams: bigramsCode, Words: cppKeywords, Symbols: cppSymbols, Snippets: cppSnippets},
+	{Name: LangJavascript, Bigrams: bigramsCode, Words: jsKeywords, Symbols: jsSymbols, Snippets: jsSnippets},
+	{Name: LangRust, Bigrams: bigramsCode, Words: rustKeywords, Symbols: rustSymbols, Snippets: rustSnippets},
+	{Name: LangPython, Bigrams: bigramsCode, Words: pythonKeywords, Symbols: pythonSymbols, Snippets: pythonSnippets},
+	{Name: LangEnglish, Natural: true, Bigrams: bigramsEnglish, Words: englishWords, Letters: "etaoinshrdlcumwfgypbvkjxqz"},
+	{Name: LangSpanish, Natural: true, Bigrams: bigramsSpanish, Words: spanishWords, Letters: "eaosrnidlctumpbgvyqhfzjñxkwáéíóúü"},
+	{Name: LangFrench, Natural: true, Bigrams: bigramsFrench, Words: frenchWords, Letters: "esaitnrulodcpmévqfbghjàxèyêzçôùâûîwk"},
+	{Name: LangGerman, Natural: true, Bigrams: bigramsGerman, Words: germanWords, Letters: "enisratdhulcgmobwfkzpvjyxqäöüß"},
+}
+
+// The registry of packs: built-ins first, then loaded packs in the order
+// they were registered.
+var (
+	packs     = make(map[Language]Pack)
+	packOrder []Language
+)
+
+func init() {
+	for _, p := range builtinPacks {
+		Register(p)
+	}
+
+	// Packs shipped as files are built in too; a pack of the same name in
+	// the user's directory, loaded later, overrides them.
+	entries, _ := fs.ReadDir(assets.Languages, "languages")
+	for _, e := range entries {
+		data, err := fs.ReadFile(assets.Languages, "languages/"+e.Name())
+		if err != nil {
+			panic(err)
+		}
+		p, err := parsePack(data, e.Name())
+		if err == nil {
+			err = Register(p)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("built-in language pack %s: %v", e.Name(), err))
+		}
+	}
+}
+
+// Register adds a pack to the registry. A pack named like one already
+// registered, built-in or not, replaces it.
+func Register(p Pack) error {
+	p.Name = Language(strings.ToLower(strings.TrimSpace(string(p.Name))))
+	if p.Name == "" {
+		return errors.New("language pack has no name")
+	}
+	if len(p.Words) == 0 {
+		return fmt.Errorf("language pack %q has no words", p.Name)
+	}
+
+	if _, ok := packs[p.Name]; !ok {
+		packOrder = append(packOrder, p.Name)
+	}
+	packs[p.Name] = p
+	return nil
+}
+
+// Languages lists the registered languages.
+func Languages() []Language {
+	return append([]Language(nil), packOrder...)
+}
+
+// LookupPack returns the pack of a registered language.
+func LookupPack(lang Language) (Pack, bool) {
+	p, ok := packs[lang]
+	return p, ok
+}
+
+// ReadPack reads a pack from a YAML or JSON file. The name defaults to
+// the file's base name.
+func ReadPack(path string) (Pack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Pack{}, err
+	}
+
+	p, err := parsePack(data, path)
+	if err != nil {
+		return Pack{}, err
+	}
+	p.Source = path
+	return p, nil
+}
+
+func parsePack(data []byte, path string) (Pack, error) {
+	var p Pack
+	// JSON is valid YAML, so one decoder reads both.
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return Pack{}, err
+	}
+	if p.Name == "" {
+		p.Name = Language(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	}
+	return p, nil
+}
+
+// LoadPacks registers every .yaml, .yml and .json pack in dir. A missing
+// dir is not an error; a broken pack is reported and skipped.
+func LoadPacks(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, e := range entries {
+		switch filepath.Ext(e.Name()) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if e.IsDir() {
+			continue
+		}
+
+		p, err := ReadPack(filepath.Join(dir, e.Name()))
+		if err == nil {
+			err = Register(p)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pack returns the current language's pack, or Go's if it has none.
+func (g *Generator) pack() Pack {
+	if p, ok := packs[g.Language]; ok {
+		return p
+	}
+	return packs[LangGo]
+}
+
+func (p Pack) bigrams() []string {
+	if len(p.Bigrams) == 0 {
+		return packs[LangGo].Bigrams
+	}
+	return p.Bigrams
+}
+
+func (p Pack) symbols() []string {
+	if len(p.Symbols) == 0 {
+		return packs[LangGo].Symbols
+	}
+	return p.Symbols
+}
+
+// letters returns the pack's letters, most common first.
+func (p Pack) letters() string {
+	if p.Letters != "" {
+		return p.Letters
+	}
+
+	counts := make(map[rune]int)
+	for _, word := range p.Words {
+		for _, r := range strings.ToLower(word) {
+			if unicode.IsLetter(r) {
+				counts[r]++
+			}
+		}
+	}
+	letters := make([]rune, 0, len(counts))
+	for r := range counts {
+		letters = append(letters, r)
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		if counts[letters[i]] != counts[letters[j]] {
+			return counts[letters[i]] > counts[letters[j]]
+		}
+		return letters[i] < letters[j]
+	})
+	return string(letters)
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withRegistry restores the built-in registry once a test is done.
+func withRegistry(t *testing.T) {
+	saved := make(map[Language]Pack, len(packs))
+	for k, v := range packs {
+		saved[k] = v
+	}
+	order := Languages()
+	t.Cleanup(func() {
+		packs, packOrder = saved, order
+	})
+}
+
+func TestLoadPacks(t *testing.T) {
+	withRegistry(t)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"kotlin.yaml": "words: [val, fun, when]\nweights: {val: 1000, fun: 1, when: 1}\n",
+		"sql.json":    `{"name": "SQL", "words": ["SELECT", "FROM"], "snippets": ["SELECT 1;"]}`,
+		"broken.yml":  "name: broken\nsymbols: [\"{}\"]\n",
+		"notes.txt":   "not a pack",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := Languages()
+	err := LoadPacks(dir)
+	if err == nil || !strings.Contains(err.Error(), "broken.yml") {
+		t.Errorf("Expected an error naming broken.yml, got %v", err)
+	}
+
+	// Both packs override built-ins of the same name, in place.
+	langs := Languages()
+	if strings.Join(langTexts(langs), " ") != strings.Join(langTexts(before), " ") {
+		t.Fatalf("Expected the user packs to replace the built-ins, got %v", langs)
+	}
+	kotlin, ok := LookupPack("kotlin")
+	if !ok || kotlin.Source != filepath.Join(dir, "kotlin.yaml") {
+		t.Errorf("Expected kotlin named after its file, got %+v", kotlin)
+	}
+	if _, ok := LookupPack("sql"); !ok {
+		t.Error("Expected pack names to be lower-cased")
+	}
+
+	g := New()
+	g.rand = rand.New(rand.NewSource(1))
+	g.SetLanguage("kotlin")
+	if n := strings.Count(g.GenerateLesson(TypeWords, 100), "val"); n < 90 {
+		t.Errorf("Expected weights to favour val, got it %d/100 times", n)
+	}
+	if lesson := g.GenerateLesson(TypeSymbols, 5); !strings.Contains(strings.Join(goSymbols, " "), strings.Fields(lesson)[0]) {
+		t.Errorf("Expected symbols borrowed from Go, got %q", lesson)
+	}
+
+	g.SetLanguage("sql")
+	if lesson := g.GenerateLesson(TypeCode, 1); lesson != "SELECT 1;" {
+		t.Errorf("Expected the pack's snippet, got %q", lesson)
+	}
+
+	if err := LoadPacks(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("Expected a missing directory to be ignored, got %v", err)
+	}
+}
+
+func langTexts(langs []Language) []string {
+	texts := make([]string, len(langs))
+	for i, l := range langs {
+		texts[i] = string(l)
+	}
+	return texts
+}
+
+func TestShippedPacks(t *testing.T) {
+	// The packs under assets/languages are built in, with nothing loaded.
+	for _, lang := range []Language{"typescript", "java", "kotlin", "sql", "bash", "portuguese"} {
+		p, ok := LookupPack(lang)
+		if !ok {
+			t.Errorf("Expected a built-in %s pack", lang)
+			continue
+		}
+		if p.Source != "" {
+			t.Errorf("Expected %s to be built in, got it from %s", lang, p.Source)
+		}
+		if !p.Natural && len(p.Snippets) == 0 {
+			t.Errorf("Pack %s has no snippets", lang)
+		}
+	}
+
+	if letters := LetterFrequency("portuguese"); !strings.HasPrefix(letters, "aeo") {
+		t.Errorf("Expected Portuguese letters, got %q", letters)
+	}
+}
@@ -0,0 +1,31 @@
+package keyboard
+
+import "sort"
+
+const DefaultLayout = "qwerty"
+
+// homeRows holds the home-row letters of each supported layout: the keys
+// a key-unlock course starts with.
+var homeRows = map[string]string{
+	"qwerty":  "asdfghjkl",
+	"qwertz":  "asdfghjkl",
+	"azerty":  "qsdfghjklm",
+	"dvorak":  "aoeuidhtns",
+	"colemak": "arstdhneio",
+}
+
+// HomeRow returns the home-row letters of a layout.
+func HomeRow(layout string) (string, bool) {
+	keys, ok := homeRows[layout]
+	return keys, ok
+}
+
+// Layouts returns the names of the supported layouts.
+func Layouts() []string {
+	names := make([]string, 0, len(homeRows))
+	for name := range homeRows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,132 @@
+package progression
+
+import (
+	"fmt"
+
+	"kata/pkg/generator"
+	"kata/pkg/keyboard"
+	"kata/pkg/stats"
+)
+
+// KeyTarget is what every unlocked key must reach in key_stats before the
+// next key unlocks.
+type KeyTarget struct {
+	Attempts     int
+	Accuracy     float64 // percent
+	MaxLatencyMs float64 // mean time to reach the key, about 35 WPM
+}
+
+var UnlockTarget = KeyTarget{Attempts: 20, Accuracy: 95, MaxLatencyMs: 350}
+
+// KeySet is the key-unlock course of one layout and language.
+type KeySet struct {
+	Layout   string
+	Language string
+	Keys     []string // unlocked, in unlock order
+	Locked   []string // still to come, most frequent first
+	Initial  int      // how many of Keys are the layout's home row
+}
+
+// Focus is the newest unlocked key, which lessons drill hardest, or ""
+// while only the home row is unlocked.
+func (k KeySet) Focus() string {
+	if len(k.Keys) <= k.Initial {
+		return ""
+	}
+	return k.Keys[len(k.Keys)-1]
+}
+
+// Pending returns the unlocked keys that have not reached UnlockTarget.
+func (k KeySet) Pending(keyStats []stats.KeyStat, latencies map[string]stats.LatencyStat) []string {
+	byKey := make(map[string]stats.KeyStat)
+	for _, s := range keyStats {
+		byKey[s.Key] = s
+	}
+
+	var pending []string
+	for _, key := range k.Keys {
+		s := byKey[key]
+		attempts := s.Errors + s.Successes
+		latency, timed := latencies[key]
+		switch {
+		case attempts < UnlockTarget.Attempts,
+			float64(s.Successes)/float64(attempts)*100 < UnlockTarget.Accuracy,
+			!timed || latency.MeanMs > UnlockTarget.MaxLatencyMs:
+			pending = append(pending, key)
+		}
+	}
+	return pending
+}
+
+// LoadKeys returns the unlocked keys of a layout and language, starting a
+// new course at the home row.
+func LoadKeys(db *stats.DB, layout, language string) (KeySet, error) {
+	home, ok := keyboard.HomeRow(layout)
+	if !ok {
+		return KeySet{}, fmt.Errorf("unknown keyboard layout %q", layout)
+	}
+
+	keys, err := db.GetUnlockedKeys(layout, language)
+	if err != nil {
+		return KeySet{}, err
+	}
+	if len(keys) == 0 {
+		for _, r := range home {
+			keys = append(keys, string(r))
+		}
+		if err := db.UnlockKeys(layout, language, keys); err != nil {
+			return KeySet{}, err
+		}
+	}
+
+	return newKeySet(layout, language, home, keys), nil
+}
+
+func newKeySet(layout, language, home string, keys []string) KeySet {
+	k := KeySet{Layout: layout, Language: language, Keys: keys}
+
+	unlocked := make(map[string]bool)
+	for _, key := range keys {
+		unlocked[key] = true
+	}
+	for _, r := range home {
+		if unlocked[string(r)] {
+			k.Initial++
+		}
+	}
+	for _, r := range generator.LetterFrequency(generator.Language(language)) {
+		if !unlocked[string(r)] {
+			k.Locked = append(k.Locked, string(r))
+		}
+	}
+	return k
+}
+
+// AdvanceKeys unlocks the next key once every unlocked key has reached
+// UnlockTarget. It returns the updated set and the new key, or "" when
+// nothing was unlocked.
+func AdvanceKeys(db *stats.DB, k KeySet) (KeySet, string, error) {
+	if len(k.Locked) == 0 {
+		return k, "", nil
+	}
+
+	keyStats, err := db.GetAllKeyStats()
+	if err != nil {
+		return k, "", err
+	}
+	latencies, err := db.GetKeyLatencies()
+	if err != nil {
+		return k, "", err
+	}
+	if len(k.Pending(keyStats, latencies)) > 0 {
+		return k, "", nil
+	}
+
+	key := k.Locked[0]
+	if err := db.UnlockKeys(k.Layout, k.Language, []string{key}); err != nil {
+		return k, "", err
+	}
+	k.Keys = append(k.Keys, key)
+	k.Locked = k.Locked[1:]
+	return k, key, nil
+}
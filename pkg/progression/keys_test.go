@@ -0,0 +1,99 @@
+package progression
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"kata/pkg/stats"
+)
+
+func TestKeySetPending(t *testing.T) {
+	k := newKeySet("qwerty", "english", "asdfghjkl", []string{"a", "s", "d", "e"})
+
+	if k.Initial != 3 || k.Focus() != "e" || k.Locked[0] != "t" {
+		t.Fatalf("Unexpected key set: %+v", k)
+	}
+
+	keyStats := []stats.KeyStat{
+		{Key: "a", Successes: 40},
+		{Key: "s", Successes: 30, Errors: 5}, // 86% accuracy
+		{Key: "d", Successes: 40},
+		{Key: "e", Successes: 10}, // too few attempts
+	}
+	latencies := map[string]stats.LatencyStat{
+		"a": {MeanMs: 200},
+		"s": {MeanMs: 200},
+		"d": {MeanMs: 500}, // too slow
+		"e": {MeanMs: 200},
+	}
+
+	if got, want := k.Pending(keyStats, latencies), []string{"s", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v pending, got %v", want, got)
+	}
+}
+
+func TestLoadAndAdvanceKeys(t *testing.T) {
+	tmpDB := "/tmp/kata_test_progression_keys.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := stats.NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := LoadKeys(db, "bepo", "english"); err == nil {
+		t.Error("Expected an unknown layout to be rejected")
+	}
+
+	k, err := LoadKeys(db, "colemak", "english")
+	if err != nil {
+		t.Fatalf("LoadKeys failed: %v", err)
+	}
+	if len(k.Keys) != 10 || k.Focus() != "" || k.Locked[0] != "l" {
+		t.Fatalf("Expected the Colemak home row with l next, got %+v", k)
+	}
+
+	k, key, err := AdvanceKeys(db, k)
+	if err != nil || key != "" {
+		t.Fatalf("Expected nothing to unlock without practice, got %q (err %v)", key, err)
+	}
+
+	// Type the home row 21 times, accurately and at 200ms per key.
+	text := strings.Repeat("arstdhneio", 21)
+	start := time.Now()
+	var keystrokes []stats.Keystroke
+	for i, r := range text {
+		keystrokes = append(keystrokes, stats.Keystroke{
+			Position:  i,
+			Expected:  string(r),
+			Typed:     string(r),
+			Kind:      stats.KeystrokeInsert,
+			Timestamp: start.Add(time.Duration(i) * 200 * time.Millisecond),
+		})
+	}
+	session := stats.Session{Text: text, WPM: 60, Accuracy: 100, Passed: true, Timestamp: start}
+	if _, err := db.SaveSessionWithKeystrokes(session, keystrokes); err != nil {
+		t.Fatalf("SaveSessionWithKeystrokes failed: %v", err)
+	}
+	if err := db.UpdateKeyStats(text, text); err != nil {
+		t.Fatalf("UpdateKeyStats failed: %v", err)
+	}
+	if err := db.RebuildLatencyStats(); err != nil {
+		t.Fatalf("RebuildLatencyStats failed: %v", err)
+	}
+
+	k, key, err = AdvanceKeys(db, k)
+	if err != nil || key != "l" || k.Focus() != "l" {
+		t.Fatalf("Expected l to unlock, got %q (err %v)", key, err)
+	}
+
+	reloaded, err := LoadKeys(db, "colemak", "english")
+	if err != nil || !reflect.DeepEqual(reloaded.Keys, k.Keys) {
+		t.Errorf("Expected the unlock to persist, got %+v (err %v)", reloaded, err)
+	}
+}
@@ -0,0 +1,146 @@
+// Package progression walks the user through the four levels of the
+// curriculum: bigrams, whole words, programming symbols and real code.
+// The current level is derived from the session history, so it needs no
+// storage of its own.
+package progression
+
+import (
+	"fmt"
+
+	"kata/pkg/generator"
+	"kata/pkg/stats"
+)
+
+// Target is what it takes to leave a level: Window consecutive lessons at
+// that level, all passing the accuracy gate, averaging at least Accuracy
+// and WPM.
+type Target struct {
+	Accuracy float64
+	WPM      float64
+}
+
+// Window is how many recent lessons a Target is measured over.
+const Window = 5
+
+type Level struct {
+	Number int
+	Name   string
+	Lesson generator.LessonType
+	Length int    // items per generated lesson
+	Target Target // zero for the last level
+}
+
+var Levels = []Level{
+	{1, "Home row & bigrams", generator.TypeBigrams, 20, Target{Accuracy: 95, WPM: 25}},
+	{2, "Words", generator.TypeWords, 15, Target{Accuracy: 95, WPM: 30}},
+	{3, "Symbols", generator.TypeSymbols, 10, Target{Accuracy: 95, WPM: 20}},
+	{4, "The Code", generator.TypeCode, 2, Target{}},
+}
+
+// Progress is where the user stands in one language.
+type Progress struct {
+	Language string
+	Level    Level
+
+	// The lessons counted towards the next level, oldest first.
+	Recent       int
+	Failed       int
+	MeanWPM      float64
+	MeanAccuracy float64
+}
+
+// Final reports whether the last level has been reached.
+func (p Progress) Final() bool {
+	return p.Level.Number == len(Levels)
+}
+
+// Next returns the level that the current one unlocks.
+func (p Progress) Next() (Level, bool) {
+	if p.Final() {
+		return Level{}, false
+	}
+	return Levels[p.Level.Number], true
+}
+
+// Status describes the way to the next level, e.g.
+// "3/5 lessons, 28/30 WPM, 97.0/95% accuracy, 1 failed".
+func (p Progress) Status() string {
+	if p.Final() {
+		return "final level reached"
+	}
+
+	t := p.Level.Target
+	status := fmt.Sprintf("%d/%d lessons, %.0f/%.0f WPM, %.1f/%.0f%% accuracy",
+		p.Recent, Window, p.MeanWPM, t.WPM, p.MeanAccuracy, t.Accuracy)
+	if p.Failed > 0 {
+		status += fmt.Sprintf(", %d failed", p.Failed)
+	}
+	return status
+}
+
+// Compute replays the sessions, oldest first, and returns the level they
+// reach in language. Only plain lessons of the current level's type count;
+// tests and other lesson types are ignored.
+func Compute(language string, sessions []stats.Session) Progress {
+	p := Progress{Language: language, Level: Levels[0]}
+
+	var window []stats.Session
+	for _, s := range sessions {
+		if p.Final() {
+			break
+		}
+		if s.Language != language || s.TestMode != "" || s.Mode != p.Level.Lesson.String() {
+			continue
+		}
+
+		window = append(window, s)
+		if len(window) > Window {
+			window = window[1:]
+		}
+		if met(p.Level.Target, window) {
+			p.Level = Levels[p.Level.Number]
+			window = nil
+		}
+	}
+
+	p.Recent = len(window)
+	for _, s := range window {
+		if !s.Passed {
+			p.Failed++
+		}
+		p.MeanWPM += s.WPM
+		p.MeanAccuracy += s.Accuracy
+	}
+	if len(window) > 0 {
+		p.MeanWPM /= float64(len(window))
+		p.MeanAccuracy /= float64(len(window))
+	}
+
+	return p
+}
+
+func met(t Target, window []stats.Session) bool {
+	if len(window) < Window {
+		return false
+	}
+
+	var wpm, accuracy float64
+	for _, s := range window {
+		if !s.Passed {
+			return false
+		}
+		wpm += s.WPM
+		accuracy += s.Accuracy
+	}
+	n := float64(len(window))
+	return wpm/n >= t.WPM && accuracy/n >= t.Accuracy
+}
+
+// Load computes the progress in language from the stored sessions.
+func Load(db *stats.DB, language string) (Progress, error) {
+	sessions, err := db.QuerySessions(stats.SessionFilter{Language: language, Ascending: true})
+	if err != nil {
+		return Progress{}, err
+	}
+	return Compute(language, sessions), nil
+}
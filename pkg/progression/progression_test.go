@@ -0,0 +1,98 @@
+package progression
+
+import (
+	"testing"
+
+	"kata/pkg/stats"
+)
+
+func lessons(mode string, n int, wpm, accuracy float64, passed bool) []stats.Session {
+	sessions := make([]stats.Session, n)
+	for i := range sessions {
+		sessions[i] = stats.Session{Mode: mode, Language: "go", WPM: wpm, Accuracy: accuracy, Passed: passed}
+	}
+	return sessions
+}
+
+func TestCompute(t *testing.T) {
+	join := func(parts ...[]stats.Session) []stats.Session {
+		var all []stats.Session
+		for _, p := range parts {
+			all = append(all, p...)
+		}
+		return all
+	}
+
+	cases := []struct {
+		name     string
+		sessions []stats.Session
+		level    int
+		recent   int
+	}{
+		{"no history", nil, 1, 0},
+		{"not enough lessons", lessons("bigrams", 4, 40, 98, true), 1, 4},
+		{"sustained targets", lessons("bigrams", 5, 40, 98, true), 2, 0},
+		{"too slow", lessons("bigrams", 8, 20, 98, true), 1, 5},
+		{"a failure in the window", join(
+			lessons("bigrams", 4, 40, 98, true),
+			lessons("bigrams", 1, 40, 80, false),
+		), 1, 5},
+		{"recovering after a failure", join(
+			lessons("bigrams", 1, 40, 80, false),
+			lessons("bigrams", 5, 40, 98, true),
+		), 2, 0},
+		{"other lesson types ignored", join(
+			lessons("symbols", 5, 40, 98, true),
+			lessons("bigrams", 3, 40, 98, true),
+		), 1, 3},
+		{"all the way", join(
+			lessons("bigrams", 5, 40, 98, true),
+			lessons("keywords", 5, 40, 98, true),
+			lessons("symbols", 5, 40, 98, true),
+			lessons("code", 5, 10, 50, false),
+		), 4, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Compute("go", tc.sessions)
+			if p.Level.Number != tc.level || p.Recent != tc.recent {
+				t.Errorf("Expected level %d with %d recent lessons, got level %d with %d",
+					tc.level, tc.recent, p.Level.Number, p.Recent)
+			}
+		})
+	}
+}
+
+func TestComputeIsPerLanguage(t *testing.T) {
+	sessions := lessons("bigrams", 5, 40, 98, true)
+	for i := range sessions {
+		sessions[i].Language = "rust"
+	}
+
+	if p := Compute("go", sessions); p.Level.Number != 1 {
+		t.Errorf("Expected Rust lessons not to advance Go, got level %d", p.Level.Number)
+	}
+	if p := Compute("rust", sessions); p.Level.Number != 2 {
+		t.Errorf("Expected Rust to reach level 2, got level %d", p.Level.Number)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	p := Compute("go", lessons("bigrams", 3, 28, 97, true))
+	if got, want := p.Status(), "3/5 lessons, 28/25 WPM, 97.0/95% accuracy"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestNext(t *testing.T) {
+	p := Progress{Level: Levels[0]}
+	if next, ok := p.Next(); !ok || next.Number != 2 {
+		t.Errorf("Expected level 2 after level 1, got %+v", next)
+	}
+
+	p.Level = Levels[len(Levels)-1]
+	if _, ok := p.Next(); ok || !p.Final() {
+		t.Error("Expected no level after the last one")
+	}
+}
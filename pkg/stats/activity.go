@@ -0,0 +1,116 @@
+package stats
+
+import "time"
+
+// DayActivity is the practice done on one calendar day.
+type DayActivity struct {
+	Date     time.Time // midnight in the requested location
+	Sessions int
+	Seconds  float64
+}
+
+// Streaks counts consecutive days with at least one session.
+type Streaks struct {
+	Current int
+	Longest int
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// GetDailyActivity returns one entry per day from the day containing from
+// through the day containing to, including days without practice. Day
+// boundaries are midnight in loc (time.Local when nil).
+func (db *DB) GetDailyActivity(from, to time.Time, loc *time.Location) ([]DayActivity, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	first := startOfDay(from, loc)
+	last := startOfDay(to, loc)
+
+	groups, err := db.AggregateSessions(SessionFilter{From: first, To: last.AddDate(0, 0, 1)}, GroupDay, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	byDay := make(map[string]Aggregate, len(groups))
+	for _, g := range groups {
+		byDay[g.Group] = g
+	}
+
+	var days []DayActivity
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		g := byDay[d.Format("2006-01-02")]
+		days = append(days, DayActivity{Date: d, Sessions: g.Count, Seconds: g.TotalDuration})
+	}
+
+	return days, nil
+}
+
+// GetStreaks computes the current and longest practice streaks as of now.
+func (db *DB) GetStreaks(now time.Time, loc *time.Location) (Streaks, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	// Only the times are needed, not whole sessions.
+	rows, err := db.conn.Query(`SELECT timestamp FROM sessions ORDER BY timestamp ASC`)
+	if err != nil {
+		return Streaks{}, err
+	}
+	defer rows.Close()
+
+	var active []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return Streaks{}, err
+		}
+		day := startOfDay(t, loc)
+		if len(active) == 0 || !active[len(active)-1].Equal(day) {
+			active = append(active, day)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Streaks{}, err
+	}
+
+	return ComputeStreaks(active, startOfDay(now, loc)), nil
+}
+
+// ComputeStreaks takes the distinct practice days in ascending order. A
+// streak that ended yesterday is still current, since today's session may
+// simply not have happened yet.
+func ComputeStreaks(activeDays []time.Time, today time.Time) Streaks {
+	var s Streaks
+	run := 0
+
+	for i, day := range activeDays {
+		if i > 0 && sameDay(activeDays[i-1].AddDate(0, 0, 1), day) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > s.Longest {
+			s.Longest = run
+		}
+	}
+
+	if len(activeDays) > 0 {
+		lastDay := activeDays[len(activeDays)-1]
+		if sameDay(lastDay, today) || sameDay(lastDay.AddDate(0, 0, 1), today) {
+			s.Current = run
+		}
+	}
+
+	return s
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,93 @@
+package stats
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestComputeStreaks(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	cases := []struct {
+		name    string
+		days    []time.Time
+		today   time.Time
+		current int
+		longest int
+	}{
+		{"no practice", nil, day(10), 0, 0},
+		{"practised today", []time.Time{day(8), day(9), day(10)}, day(10), 3, 3},
+		{"not yet today", []time.Time{day(8), day(9)}, day(10), 2, 2},
+		{"broken streak", []time.Time{day(1), day(2), day(3), day(4), day(7)}, day(10), 0, 4},
+		{"restarted", []time.Time{day(1), day(2), day(3), day(9), day(10)}, day(10), 2, 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := ComputeStreaks(tc.days, tc.today)
+			if s.Current != tc.current || s.Longest != tc.longest {
+				t.Errorf("Expected current %d longest %d, got %+v", tc.current, tc.longest, s)
+			}
+		})
+	}
+}
+
+func TestDailyActivityAndStreaks(t *testing.T) {
+	tmpDB := "/tmp/kata_test_activity.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	// 23:30 UTC is already the next day in UTC+2.
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	timestamps := []time.Time{
+		time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 1, 23, 30, 0, 0, time.UTC),
+		time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC),
+	}
+	for _, ts := range timestamps {
+		if err := db.SaveSession(Session{Text: "x", WPM: 50, Duration: 60, Timestamp: ts}); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+
+	days, err := db.GetDailyActivity(time.Date(2025, 3, 1, 12, 0, 0, 0, zone), time.Date(2025, 3, 4, 12, 0, 0, 0, zone), zone)
+	if err != nil {
+		t.Fatalf("GetDailyActivity failed: %v", err)
+	}
+
+	expected := []int{1, 1, 1, 0}
+	if len(days) != len(expected) {
+		t.Fatalf("Expected %d days, got %d", len(expected), len(days))
+	}
+	for i, d := range days {
+		if d.Sessions != expected[i] {
+			t.Errorf("Day %s: expected %d sessions, got %d", d.Date.Format("Jan 02"), expected[i], d.Sessions)
+		}
+	}
+	if days[0].Seconds != 60 {
+		t.Errorf("Expected 60s on the first day, got %.0f", days[0].Seconds)
+	}
+
+	streaks, err := db.GetStreaks(time.Date(2025, 3, 4, 9, 0, 0, 0, zone), zone)
+	if err != nil {
+		t.Fatalf("GetStreaks failed: %v", err)
+	}
+	if streaks.Current != 3 || streaks.Longest != 3 {
+		t.Errorf("Expected a 3 day streak in UTC+2, got %+v", streaks)
+	}
+
+	streaks, err = db.GetStreaks(time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC), time.UTC)
+	if err != nil {
+		t.Fatalf("GetStreaks failed: %v", err)
+	}
+	if streaks.Current != 1 || streaks.Longest != 1 {
+		t.Errorf("Expected a 1 day streak in UTC, got %+v", streaks)
+	}
+}
@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"time"
+
+	"kata/pkg/align"
+)
+
+// UpdateBigramStats records how each transition in the target was typed.
+// A bigram counts as an error when its second rune was mistyped, and as a
+// success when both runes were typed correctly.
+func (db *DB) UpdateBigramStats(target, input string) error {
+	return db.UpdateBigramStatsWithKeystrokes(target, input, nil)
+}
+
+// UpdateBigramStatsWithKeystrokes is UpdateBigramStats with the session's
+// keystroke log, whose latencies are used to grade slow transitions down.
+func (db *DB) UpdateBigramStatsWithKeystrokes(target, input string, keystrokes []Keystroke) error {
+	targetRunes := []rune(target)
+	outcomes := align.Outcomes(align.Align(targetRunes, []rune(input)), len(targetRunes))
+
+	bigramStats := make(map[string]attemptCounts)
+
+	for i := 1; i < len(outcomes); i++ {
+		bigram := string(targetRunes[i-1 : i+1])
+		stats := bigramStats[bigram]
+
+		if outcomes[i] == align.Wrong {
+			stats.errors++
+		} else if outcomes[i] == align.Correct && outcomes[i-1] == align.Correct {
+			stats.successes++
+		} else {
+			continue
+		}
+
+		bigramStats[bigram] = stats
+	}
+
+	_, latencies := LatencySamples(keystrokes)
+	addLatencies(bigramStats, latencies)
+
+	return db.applySRSUpdates(bigramTable, bigramStats)
+}
+
+func (db *DB) GetWeakestBigrams(limit int) ([]KeyStat, error) {
+	query := `
+	SELECT bigram, ` + keyStatColumns + `
+	FROM bigram_stats
+	WHERE (errors + successes) >= 5 AND errors > 0
+	ORDER BY CAST(errors AS REAL) / (errors + successes) DESC
+	LIMIT ?
+	`
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanKeyStats(rows)
+}
+
+// WeakBigramWeights returns the error rates of the weakest bigrams,
+// scaled so the weakest one weighs 1.
+func (db *DB) WeakBigramWeights(limit int) (map[string]float64, error) {
+	weakest, err := db.GetWeakestBigrams(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := make(map[string]float64, len(weakest))
+	var highest float64
+	for _, k := range weakest {
+		rate := float64(k.Errors) / float64(k.Errors+k.Successes)
+		weights[k.Key] = rate
+		highest = max(highest, rate)
+	}
+	for k := range weights {
+		weights[k] /= highest
+	}
+	return weights, nil
+}
+
+// GetDueBigrams returns bigrams whose SM-2 interval has elapsed. Only
+// bigrams that have been missed at least once are scheduled, so the queue
+// holds the transitions that actually need work.
+func (db *DB) GetDueBigrams(limit int) ([]KeyStat, error) {
+	query := `
+	SELECT bigram, ` + keyStatColumns + `
+	FROM bigram_stats
+	WHERE (errors + successes) >= 3 AND errors > 0
+	ORDER BY COALESCE(last_reviewed, last_practiced) ASC
+	`
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	all, err := scanKeyStats(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var stats []KeyStat
+	for _, s := range all {
+		daysSince := now.Sub(s.LastReviewed).Hours() / 24
+		if daysSince >= float64(s.Interval) {
+			stats = append(stats, s)
+			if len(stats) >= limit {
+				break
+			}
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,113 @@
+package stats
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUpdateBigramStats(t *testing.T) {
+	tmpDB := "/tmp/kata_test_bigrams.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	// "p{" is mistyped as "p[", the other transitions are clean.
+	if err := db.UpdateBigramStats("ap{", "ap["); err != nil {
+		t.Fatalf("UpdateBigramStats failed: %v", err)
+	}
+
+	rows, err := db.conn.Query(`SELECT bigram, errors, successes, interval, repetitions FROM bigram_stats`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+
+	got := make(map[string][4]int)
+	for rows.Next() {
+		var bigram string
+		var errors, successes, interval, reps int
+		if err := rows.Scan(&bigram, &errors, &successes, &interval, &reps); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		got[bigram] = [4]int{errors, successes, interval, reps}
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 bigrams, got %d: %v", len(got), got)
+	}
+	if got["ap"] != [4]int{0, 1, 1, 1} {
+		t.Errorf("Unexpected stats for 'ap': %v", got["ap"])
+	}
+	if got["p{"][0] != 1 || got["p{"][1] != 0 || got["p{"][3] != 0 {
+		t.Errorf("Expected 'p{' to be a failed review, got %v", got["p{"])
+	}
+}
+
+func TestBigramQueues(t *testing.T) {
+	tmpDB := "/tmp/kata_test_bigram_queues.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	testData := []struct {
+		bigram        string
+		errors        int
+		successes     int
+		lastPracticed time.Time
+		interval      int
+	}{
+		{"p{", 6, 4, now.Add(-3 * 24 * time.Hour), 1},   // weakest, due
+		{"er", 2, 8, now.Add(-1 * time.Hour), 1},        // not due yet
+		{"th", 0, 20, now.Add(-30 * 24 * time.Hour), 1}, // never missed, not queued
+		{":=", 3, 7, now.Add(-8 * 24 * time.Hour), 6},   // due
+	}
+
+	for _, td := range testData {
+		_, err := db.conn.Exec(`
+			INSERT INTO bigram_stats (bigram, errors, successes, last_practiced, interval, repetitions, ease_factor)
+			VALUES (?, ?, ?, ?, ?, 1, 2.5)
+		`, td.bigram, td.errors, td.successes, td.lastPracticed, td.interval)
+		if err != nil {
+			t.Fatalf("Insert failed for '%s': %v", td.bigram, err)
+		}
+	}
+
+	weakest, err := db.GetWeakestBigrams(2)
+	if err != nil {
+		t.Fatalf("GetWeakestBigrams failed: %v", err)
+	}
+	if len(weakest) != 2 || weakest[0].Key != "p{" || weakest[1].Key != ":=" {
+		t.Errorf("Expected [p{ :=] as weakest bigrams, got %v", weakest)
+	}
+
+	weights, err := db.WeakBigramWeights(10)
+	if err != nil {
+		t.Fatalf("WeakBigramWeights failed: %v", err)
+	}
+	if len(weights) != 3 || weights["p{"] != 1 || weights[":="] != 0.5 {
+		t.Errorf("Expected weights scaled to the weakest bigram, got %v", weights)
+	}
+
+	due, err := db.GetDueBigrams(10)
+	if err != nil {
+		t.Fatalf("GetDueBigrams failed: %v", err)
+	}
+	if len(due) != 2 {
+		t.Fatalf("Expected 2 due bigrams, got %d", len(due))
+	}
+	if due[0].Key != ":=" || due[1].Key != "p{" {
+		t.Errorf("Expected due bigrams ordered oldest first [:= p{], got [%s %s]", due[0].Key, due[1].Key)
+	}
+}
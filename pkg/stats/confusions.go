@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"time"
+
+	"kata/pkg/align"
+)
+
+// Confusion counts how often Typed was pressed where Expected was wanted.
+type Confusion struct {
+	Expected string
+	Typed    string
+	Count    int
+	// Rate is Count relative to all attempts at Expected recorded in
+	// key_stats, 0 when the key has no stats.
+	Rate float64
+}
+
+// UpdateConfusions adds the session's substitutions to the confusion
+// matrix. The ones left in the input come from aligning it against the
+// target, so a skipped or doubled rune does not read as a run of wrong
+// keys. Corrected mistakes count too, from the deletions and rejections in
+// the keystroke log: the wrong key was still reached for.
+func (db *DB) UpdateConfusions(target, input string, keystrokes []Keystroke) error {
+	counts := make(map[[2]string]int)
+	for _, ed := range align.Align([]rune(target), []rune(input)) {
+		if ed.Op == align.Substitution {
+			counts[[2]string{string(ed.Expected), string(ed.Typed)}]++
+		}
+	}
+	for _, k := range keystrokes {
+		if k.Kind != KeystrokeDelete && k.Kind != KeystrokeRejected {
+			continue
+		}
+		if k.Expected != "" && k.Typed != k.Expected {
+			counts[[2]string{k.Expected, k.Typed}]++
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO confusions (expected, typed, count, last_seen)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT(expected, typed) DO UPDATE SET
+		count = count + excluded.count,
+		last_seen = excluded.last_seen
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for pair, n := range counts {
+		if _, err := stmt.Exec(pair[0], pair[1], n, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetTopConfusions returns the most frequent expected→typed pairs.
+func (db *DB) GetTopConfusions(limit int) ([]Confusion, error) {
+	query := `
+	SELECT c.expected, c.typed, c.count,
+		COALESCE(CAST(c.count AS REAL) / NULLIF(k.errors + k.successes, 0), 0)
+	FROM confusions c
+	LEFT JOIN key_stats k ON k.key = c.expected
+	ORDER BY c.count DESC, c.last_seen DESC
+	LIMIT ?
+	`
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var confusions []Confusion
+	for rows.Next() {
+		var c Confusion
+		if err := rows.Scan(&c.Expected, &c.Typed, &c.Count, &c.Rate); err != nil {
+			return nil, err
+		}
+		confusions = append(confusions, c)
+	}
+
+	return confusions, rows.Err()
+}
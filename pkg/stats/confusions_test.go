@@ -0,0 +1,80 @@
+package stats
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConfusions(t *testing.T) {
+	tmpDB := "/tmp/kata_test_confusions.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	// "{" typed as "[", deleted, then fixed; "t" typed as "r" and left.
+	session := []Keystroke{
+		{Position: 0, Expected: "{", Typed: "[", Kind: KeystrokeInsert, Timestamp: now},
+		{Position: 0, Expected: "{", Typed: "[", Kind: KeystrokeDelete, Timestamp: now},
+		{Position: 0, Expected: "{", Typed: "{", Kind: KeystrokeCorrection, Timestamp: now},
+		{Position: 1, Expected: "t", Typed: "r", Kind: KeystrokeInsert, Timestamp: now},
+		{Position: 2, Expected: "", Typed: "x", Kind: KeystrokeInsert, Timestamp: now},
+	}
+	for i := 0; i < 2; i++ {
+		if err := db.UpdateConfusions("{t", "{r", session); err != nil {
+			t.Fatalf("UpdateConfusions failed: %v", err)
+		}
+	}
+	if err := db.UpdateConfusions("", "", session[1:2]); err != nil {
+		t.Fatalf("UpdateConfusions failed: %v", err)
+	}
+	if err := db.UpdateKeyStats("{{{{{{", "{{{{{{"); err != nil {
+		t.Fatalf("UpdateKeyStats failed: %v", err)
+	}
+
+	top, err := db.GetTopConfusions(10)
+	if err != nil {
+		t.Fatalf("GetTopConfusions failed: %v", err)
+	}
+	if len(top) != 2 {
+		t.Fatalf("Expected 2 confusions, got %+v", top)
+	}
+	if top[0].Expected != "{" || top[0].Typed != "[" || top[0].Count != 3 || top[0].Rate != 0.5 {
+		t.Errorf("Expected {→[ x3 at 50%%, got %+v", top[0])
+	}
+	if top[1].Expected != "t" || top[1].Typed != "r" || top[1].Count != 2 || top[1].Rate != 0 {
+		t.Errorf("Expected t→r x2 without key stats, got %+v", top[1])
+	}
+}
+
+func TestConfusionsAfterSkip(t *testing.T) {
+	tmpDB := "/tmp/kata_test_confusions_skip.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	// A skipped l is an omission, not a run of wrong keys after it; the
+	// a typed as r further on is the one confusion.
+	if err := db.UpdateConfusions("hello world again", "helo world agrin", nil); err != nil {
+		t.Fatalf("UpdateConfusions failed: %v", err)
+	}
+
+	top, err := db.GetTopConfusions(10)
+	if err != nil {
+		t.Fatalf("GetTopConfusions failed: %v", err)
+	}
+	if len(top) != 1 || top[0].Expected != "a" || top[0].Typed != "r" || top[0].Count != 1 {
+		t.Errorf("Expected only a→r, got %+v", top)
+	}
+}
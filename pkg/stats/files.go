@@ -0,0 +1,56 @@
+package stats
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FileProgress is how far through a file the user has typed: the chapter
+// they are on and the rune offset within it. Hash identifies the file's
+// content, so progress through an edited file is not resumed.
+type FileProgress struct {
+	Path      string
+	Hash      string
+	Chapter   int
+	Offset    int
+	UpdatedAt time.Time
+}
+
+// GetFileProgress returns the saved progress through a file, if any.
+func (db *DB) GetFileProgress(path string) (FileProgress, bool, error) {
+	var p FileProgress
+	err := db.conn.QueryRow(`
+	SELECT path, hash, chapter, offset, updated_at
+	FROM file_progress
+	WHERE path = ?
+	`, path).Scan(&p.Path, &p.Hash, &p.Chapter, &p.Offset, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return FileProgress{}, false, nil
+	}
+	if err != nil {
+		return FileProgress{}, false, err
+	}
+	return p, true, nil
+}
+
+// SaveFileProgress records the progress through a file, replacing any
+// saved before.
+func (db *DB) SaveFileProgress(p FileProgress) error {
+	_, err := db.conn.Exec(`
+	INSERT INTO file_progress (path, hash, chapter, offset, updated_at)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(path) DO UPDATE SET
+		hash = excluded.hash,
+		chapter = excluded.chapter,
+		offset = excluded.offset,
+		updated_at = excluded.updated_at
+	`, p.Path, p.Hash, p.Chapter, p.Offset, time.Now())
+	return err
+}
+
+// ClearFileProgress forgets the progress through a file, e.g. once it has
+// been typed to the end.
+func (db *DB) ClearFileProgress(path string) error {
+	_, err := db.conn.Exec(`DELETE FROM file_progress WHERE path = ?`, path)
+	return err
+}
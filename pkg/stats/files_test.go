@@ -0,0 +1,44 @@
+package stats
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFileProgress(t *testing.T) {
+	tmpDB := "/tmp/kata_test_files.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	if _, ok, err := db.GetFileProgress("/src/main.py"); err != nil || ok {
+		t.Fatalf("Expected no progress yet, got ok=%v err=%v", ok, err)
+	}
+
+	if err := db.SaveFileProgress(FileProgress{Path: "/src/main.py", Hash: "abc", Chapter: 2, Offset: 40}); err != nil {
+		t.Fatalf("SaveFileProgress failed: %v", err)
+	}
+	if err := db.SaveFileProgress(FileProgress{Path: "/src/main.py", Hash: "abc", Chapter: 3}); err != nil {
+		t.Fatalf("SaveFileProgress failed: %v", err)
+	}
+
+	p, ok, err := db.GetFileProgress("/src/main.py")
+	if err != nil || !ok {
+		t.Fatalf("Expected saved progress, got ok=%v err=%v", ok, err)
+	}
+	if p.Hash != "abc" || p.Chapter != 3 || p.Offset != 0 || p.UpdatedAt.IsZero() {
+		t.Errorf("Expected chapter 3 from the start, got %+v", p)
+	}
+
+	if err := db.ClearFileProgress("/src/main.py"); err != nil {
+		t.Fatalf("ClearFileProgress failed: %v", err)
+	}
+	if _, ok, _ := db.GetFileProgress("/src/main.py"); ok {
+		t.Error("Expected the progress to be cleared")
+	}
+}
@@ -0,0 +1,87 @@
+package stats
+
+import "time"
+
+// Keystroke kinds as stored in the keystrokes table.
+const (
+	KeystrokeInsert     = "insert"
+	KeystrokeCorrection = "correction"
+	KeystrokeDelete     = "delete"
+	KeystrokeRejected   = "rejected"
+)
+
+// Keystroke is one raw input event recorded during a session. Expected is
+// empty when the keystroke fell past the end of the target text.
+type Keystroke struct {
+	SessionID int
+	Position  int
+	Expected  string
+	Typed     string
+	Kind      string
+	Timestamp time.Time
+}
+
+// SaveSessionWithKeystrokes stores the session together with its keystroke
+// log in a single transaction and returns the new session ID.
+func (db *DB) SaveSessionWithKeystrokes(session Session, keystrokes []Keystroke) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertSession(tx, session)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(keystrokes) > 0 {
+		stmt, err := tx.Prepare(`
+		INSERT INTO keystrokes (session_id, position, expected, typed, kind, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return 0, err
+		}
+		defer stmt.Close()
+
+		for _, k := range keystrokes {
+			if _, err := stmt.Exec(id, k.Position, k.Expected, k.Typed, k.Kind, k.Timestamp); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetSessionKeystrokes returns the keystroke log of a session in the order
+// the events happened.
+func (db *DB) GetSessionKeystrokes(sessionID int) ([]Keystroke, error) {
+	query := `
+	SELECT session_id, position, expected, typed, kind, timestamp
+	FROM keystrokes
+	WHERE session_id = ?
+	ORDER BY id ASC
+	`
+	rows, err := db.conn.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keystrokes []Keystroke
+	for rows.Next() {
+		var k Keystroke
+		if err := rows.Scan(&k.SessionID, &k.Position, &k.Expected, &k.Typed, &k.Kind, &k.Timestamp); err != nil {
+			return nil, err
+		}
+		keystrokes = append(keystrokes, k)
+	}
+
+	return keystrokes, nil
+}
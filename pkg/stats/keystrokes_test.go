@@ -0,0 +1,66 @@
+package stats
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSaveSessionWithKeystrokes(t *testing.T) {
+	tmpDB := "/tmp/kata_test_keystrokes.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Now()
+	keystrokes := []Keystroke{
+		{Position: 0, Expected: "h", Typed: "h", Kind: KeystrokeInsert, Timestamp: start},
+		{Position: 1, Expected: "i", Typed: "o", Kind: KeystrokeInsert, Timestamp: start.Add(120 * time.Millisecond)},
+		{Position: 1, Expected: "i", Typed: "o", Kind: KeystrokeDelete, Timestamp: start.Add(300 * time.Millisecond)},
+		{Position: 1, Expected: "i", Typed: "i", Kind: KeystrokeCorrection, Timestamp: start.Add(450 * time.Millisecond)},
+	}
+
+	id, err := db.SaveSessionWithKeystrokes(Session{
+		Text:      "hi",
+		WPM:       40,
+		Accuracy:  75,
+		Duration:  0.45,
+		Timestamp: start,
+	}, keystrokes)
+	if err != nil {
+		t.Fatalf("SaveSessionWithKeystrokes failed: %v", err)
+	}
+
+	sessions, err := db.GetRecentSessions(1)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("GetRecentSessions failed: %v", err)
+	}
+	if sessions[0].ID != id {
+		t.Errorf("Expected session ID %d, got %d", id, sessions[0].ID)
+	}
+
+	stored, err := db.GetSessionKeystrokes(id)
+	if err != nil {
+		t.Fatalf("GetSessionKeystrokes failed: %v", err)
+	}
+
+	if len(stored) != len(keystrokes) {
+		t.Fatalf("Expected %d keystrokes, got %d", len(keystrokes), len(stored))
+	}
+
+	for i, k := range stored {
+		want := keystrokes[i]
+		if k.SessionID != id || k.Position != want.Position || k.Expected != want.Expected ||
+			k.Typed != want.Typed || k.Kind != want.Kind {
+			t.Errorf("Keystroke %d: expected %+v, got %+v", i, want, k)
+		}
+		if !k.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("Keystroke %d: expected timestamp %v, got %v", i, want.Timestamp, k.Timestamp)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// Gaps longer than this are pauses, not hesitation, and are ignored.
+	maxLatencyGap = 2 * time.Second
+	// Latency stats cover the keystrokes of this many recent sessions, so
+	// they follow the user's current speed rather than their history.
+	latencyWindowSessions = 50
+)
+
+// LatencyStat summarises how long the user takes to reach a key (or the
+// second rune of a bigram) after the previous keystroke.
+type LatencyStat struct {
+	Key     string
+	Samples int
+	MeanMs  float64
+	P50Ms   float64
+	P90Ms   float64
+}
+
+// LatencySamples extracts inter-key latencies in milliseconds from a
+// keystroke log, keyed by expected rune and by expected bigram. Only
+// correctly typed runes contribute a sample.
+func LatencySamples(keystrokes []Keystroke) (keys map[string][]float64, bigrams map[string][]float64) {
+	keys = make(map[string][]float64)
+	bigrams = make(map[string][]float64)
+
+	for i := 1; i < len(keystrokes); i++ {
+		prev, cur := keystrokes[i-1], keystrokes[i]
+		if cur.Kind == KeystrokeDelete || cur.Expected == "" || cur.Typed != cur.Expected {
+			continue
+		}
+
+		gap := cur.Timestamp.Sub(prev.Timestamp)
+		if gap <= 0 || gap > maxLatencyGap {
+			continue
+		}
+		ms := float64(gap) / float64(time.Millisecond)
+
+		keys[cur.Expected] = append(keys[cur.Expected], ms)
+
+		if prev.Kind != KeystrokeDelete && prev.Position == cur.Position-1 && prev.Typed == prev.Expected {
+			bigram := prev.Expected + cur.Expected
+			bigrams[bigram] = append(bigrams[bigram], ms)
+		}
+	}
+
+	return keys, bigrams
+}
+
+func summarizeLatency(key string, samples []float64) LatencyStat {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, s := range sorted {
+		sum += s
+	}
+
+	return LatencyStat{
+		Key:     key,
+		Samples: len(sorted),
+		MeanMs:  sum / float64(len(sorted)),
+		P50Ms:   percentile(sorted, 0.5),
+		P90Ms:   percentile(sorted, 0.9),
+	}
+}
+
+// percentile uses nearest-rank on an already sorted slice.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// RebuildLatencyStats recomputes the latency_stats table from the raw
+// keystroke log of the latencyWindowSessions most recent sessions. The
+// window is deliberate: a key that used to be slow drops out once it no
+// longer is, and percentiles need the samples anyway, so the cost of a
+// rebuild is bounded by the window rather than growing with the history.
+func (db *DB) RebuildLatencyStats() error {
+	rows, err := db.conn.Query(`
+	SELECT session_id, position, expected, typed, kind, timestamp
+	FROM keystrokes
+	WHERE session_id IN (SELECT id FROM sessions ORDER BY id DESC LIMIT ?)
+	ORDER BY session_id ASC, id ASC
+	`, latencyWindowSessions)
+	if err != nil {
+		return err
+	}
+
+	keySamples := make(map[string][]float64)
+	bigramSamples := make(map[string][]float64)
+
+	flush := func(session []Keystroke) {
+		keys, bigrams := LatencySamples(session)
+		for k, v := range keys {
+			keySamples[k] = append(keySamples[k], v...)
+		}
+		for k, v := range bigrams {
+			bigramSamples[k] = append(bigramSamples[k], v...)
+		}
+	}
+
+	var session []Keystroke
+	for rows.Next() {
+		var k Keystroke
+		if err := rows.Scan(&k.SessionID, &k.Position, &k.Expected, &k.Typed, &k.Kind, &k.Timestamp); err != nil {
+			rows.Close()
+			return err
+		}
+		if len(session) > 0 && session[0].SessionID != k.SessionID {
+			flush(session)
+			session = nil
+		}
+		session = append(session, k)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+	flush(session)
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM latency_stats`); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO latency_stats (kind, key, samples, mean_ms, p50_ms, p90_ms, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for kind, samples := range map[string]map[string][]float64{"key": keySamples, "bigram": bigramSamples} {
+		for key, values := range samples {
+			s := summarizeLatency(key, values)
+			if _, err := stmt.Exec(kind, s.Key, s.Samples, s.MeanMs, s.P50Ms, s.P90Ms, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSlowestKeys returns the keys with the highest mean latency among those
+// with at least five samples.
+func (db *DB) GetSlowestKeys(limit int) ([]LatencyStat, error) {
+	return db.getSlowest("key", limit)
+}
+
+// GetSlowestBigrams is GetSlowestKeys for transitions.
+func (db *DB) GetSlowestBigrams(limit int) ([]LatencyStat, error) {
+	return db.getSlowest("bigram", limit)
+}
+
+func (db *DB) getSlowest(kind string, limit int) ([]LatencyStat, error) {
+	query := `
+	SELECT key, samples, mean_ms, p50_ms, p90_ms
+	FROM latency_stats
+	WHERE kind = ? AND samples >= 5
+	ORDER BY mean_ms DESC
+	LIMIT ?
+	`
+	rows, err := db.conn.Query(query, kind, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []LatencyStat
+	for rows.Next() {
+		var s LatencyStat
+		if err := rows.Scan(&s.Key, &s.Samples, &s.MeanMs, &s.P50Ms, &s.P90Ms); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
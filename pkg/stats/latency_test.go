@@ -0,0 +1,143 @@
+package stats
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func keystrokesAt(start time.Time, expected, typed string, gapsMs []int) []Keystroke {
+	var ks []Keystroke
+	t := start
+	for i, r := range []rune(typed) {
+		t = t.Add(time.Duration(gapsMs[i]) * time.Millisecond)
+		ks = append(ks, Keystroke{
+			Position:  i,
+			Expected:  string([]rune(expected)[i]),
+			Typed:     string(r),
+			Kind:      KeystrokeInsert,
+			Timestamp: t,
+		})
+	}
+	return ks
+}
+
+func TestLatencySamples(t *testing.T) {
+	ks := keystrokesAt(time.Now(), "abcd", "abxd", []int{0, 100, 200, 5000})
+
+	keys, bigrams := LatencySamples(ks)
+
+	if len(keys["b"]) != 1 || keys["b"][0] != 100 {
+		t.Errorf("Expected one 100ms sample for 'b', got %v", keys["b"])
+	}
+	if len(keys["c"]) != 0 {
+		t.Errorf("Mistyped 'c' should not produce a sample, got %v", keys["c"])
+	}
+	if len(keys["d"]) != 0 {
+		t.Errorf("A 5s pause should not produce a sample, got %v", keys["d"])
+	}
+	if len(bigrams["ab"]) != 1 || bigrams["ab"][0] != 100 {
+		t.Errorf("Expected one 100ms sample for 'ab', got %v", bigrams["ab"])
+	}
+}
+
+func TestSummarizeLatency(t *testing.T) {
+	s := summarizeLatency("a", []float64{500, 100, 300, 200, 400, 600, 700, 800, 900, 1000})
+
+	if s.Samples != 10 {
+		t.Errorf("Expected 10 samples, got %d", s.Samples)
+	}
+	if s.MeanMs != 550 {
+		t.Errorf("Expected mean 550, got %.1f", s.MeanMs)
+	}
+	if s.P50Ms != 500 {
+		t.Errorf("Expected p50 500, got %.1f", s.P50Ms)
+	}
+	if s.P90Ms != 900 {
+		t.Errorf("Expected p90 900, got %.1f", s.P90Ms)
+	}
+}
+
+func TestGetSlowestKeys(t *testing.T) {
+	tmpDB := "/tmp/kata_test_latency.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	// 'j' always takes 400ms, 'f' 100ms.
+	text := "fjfjfjfjfjfj"
+	gaps := []int{0, 400, 100, 400, 100, 400, 100, 400, 100, 400, 100, 400}
+	start := time.Now()
+	ks := keystrokesAt(start, text, text, gaps)
+
+	if _, err := db.SaveSessionWithKeystrokes(Session{Text: text, Timestamp: start}, ks); err != nil {
+		t.Fatalf("SaveSessionWithKeystrokes failed: %v", err)
+	}
+	if err := db.RebuildLatencyStats(); err != nil {
+		t.Fatalf("RebuildLatencyStats failed: %v", err)
+	}
+
+	slow, err := db.GetSlowestKeys(5)
+	if err != nil {
+		t.Fatalf("GetSlowestKeys failed: %v", err)
+	}
+	if len(slow) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(slow))
+	}
+	if slow[0].Key != "j" || slow[0].MeanMs != 400 || slow[0].Samples != 6 {
+		t.Errorf("Expected 'j' as slowest at 400ms over 6 samples, got %+v", slow[0])
+	}
+	if slow[1].Key != "f" || slow[1].MeanMs != 100 {
+		t.Errorf("Expected 'f' second at 100ms, got %+v", slow[1])
+	}
+
+	bigrams, err := db.GetSlowestBigrams(5)
+	if err != nil {
+		t.Fatalf("GetSlowestBigrams failed: %v", err)
+	}
+	if len(bigrams) != 2 || bigrams[0].Key != "fj" {
+		t.Errorf("Expected 'fj' as slowest bigram, got %+v", bigrams)
+	}
+}
+
+func TestLatencyWindow(t *testing.T) {
+	tmpDB := "/tmp/kata_test_latency_window.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	// One slow session, then a full window of fast ones.
+	text := "ffffff"
+	start := time.Now()
+	save := func(gapMs int) {
+		ks := keystrokesAt(start, text, text, []int{0, gapMs, gapMs, gapMs, gapMs, gapMs})
+		if _, err := db.SaveSessionWithKeystrokes(Session{Text: text, Timestamp: start}, ks); err != nil {
+			t.Fatalf("SaveSessionWithKeystrokes failed: %v", err)
+		}
+	}
+	save(900)
+	for i := 0; i < latencyWindowSessions; i++ {
+		save(100)
+	}
+
+	if err := db.RebuildLatencyStats(); err != nil {
+		t.Fatalf("RebuildLatencyStats failed: %v", err)
+	}
+	slow, err := db.GetSlowestKeys(1)
+	if err != nil {
+		t.Fatalf("GetSlowestKeys failed: %v", err)
+	}
+	if len(slow) != 1 || slow[0].MeanMs != 100 || slow[0].Samples != 5*latencyWindowSessions {
+		t.Errorf("Expected the session before the window to drop out, got %+v", slow)
+	}
+}
@@ -0,0 +1,314 @@
+package stats
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is one numbered schema change. Versions must be strictly
+// increasing; once released, a migration must never be edited, only
+// followed by a new one.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// Migrations 1-6 recreate the schema that used to be set up ad hoc at
+// startup. They are idempotent so databases created before schema_version
+// existed are adopted without changes.
+var migrations = []migration{
+	{1, "create sessions and key_stats", execStatements(`
+	CREATE TABLE IF NOT EXISTS sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		text TEXT NOT NULL,
+		wpm REAL NOT NULL,
+		accuracy REAL NOT NULL,
+		duration REAL NOT NULL,
+		error_count INTEGER NOT NULL,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`, `
+	CREATE TABLE IF NOT EXISTS key_stats (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		key TEXT NOT NULL,
+		errors INTEGER DEFAULT 0,
+		successes INTEGER DEFAULT 0,
+		last_practiced DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(key)
+	)`)},
+	{2, "add SM-2 columns to key_stats", addColumns("key_stats",
+		"interval INTEGER DEFAULT 0",
+		"repetitions INTEGER DEFAULT 0",
+		"ease_factor REAL DEFAULT 2.5",
+	)},
+	{3, "create keystrokes", execStatements(`
+	CREATE TABLE IF NOT EXISTS keystrokes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		expected TEXT NOT NULL,
+		typed TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		timestamp DATETIME NOT NULL
+	)`)},
+	{4, "create bigram_stats", execStatements(`
+	CREATE TABLE IF NOT EXISTS bigram_stats (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		bigram TEXT NOT NULL,
+		errors INTEGER DEFAULT 0,
+		successes INTEGER DEFAULT 0,
+		last_practiced DATETIME DEFAULT CURRENT_TIMESTAMP,
+		interval INTEGER DEFAULT 0,
+		repetitions INTEGER DEFAULT 0,
+		ease_factor REAL DEFAULT 2.5,
+		UNIQUE(bigram)
+	)`)},
+	{5, "create latency_stats", execStatements(`
+	CREATE TABLE IF NOT EXISTS latency_stats (
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		samples INTEGER NOT NULL,
+		mean_ms REAL NOT NULL,
+		p50_ms REAL NOT NULL,
+		p90_ms REAL NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(kind, key)
+	)`)},
+	{6, "create indexes", execStatements(
+		`CREATE INDEX IF NOT EXISTS idx_sessions_timestamp ON sessions(timestamp DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_key_stats_last_practiced ON key_stats(last_practiced ASC)`,
+		`CREATE INDEX IF NOT EXISTS idx_key_stats_attempts ON key_stats((errors + successes) DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_bigram_stats_last_practiced ON bigram_stats(last_practiced ASC)`,
+		`CREATE INDEX IF NOT EXISTS idx_keystrokes_session ON keystrokes(session_id)`,
+	)},
+	{7, "add session metadata", execStatements(
+		`ALTER TABLE sessions ADD COLUMN mode TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE sessions ADD COLUMN language TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE sessions ADD COLUMN source TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE sessions ADD COLUMN source_hash TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE sessions ADD COLUMN zen_mode INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN rules TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_mode_language ON sessions(mode, language)`,
+	)},
+	// Existing SM-2 state seeds FSRS: the current interval becomes the
+	// stability and the ease factor maps onto difficulty (2.5 -> 5,
+	// 1.3 -> 10). Keys never scheduled keep zero and start fresh.
+	{8, "add FSRS state", execStatements(
+		`ALTER TABLE key_stats ADD COLUMN stability REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE key_stats ADD COLUMN difficulty REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE bigram_stats ADD COLUMN stability REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE bigram_stats ADD COLUMN difficulty REAL NOT NULL DEFAULT 0`,
+		`UPDATE key_stats
+		SET stability = interval,
+			difficulty = MIN(10, MAX(1, 5 + (2.5 - ease_factor) * 5 / 1.2))
+		WHERE interval > 0`,
+		`UPDATE bigram_stats
+		SET stability = interval,
+			difficulty = MIN(10, MAX(1, 5 + (2.5 - ease_factor) * 5 / 1.2))
+		WHERE interval > 0`,
+	)},
+	{9, "add review history", execStatements(
+		`ALTER TABLE key_stats ADD COLUMN last_reviewed DATETIME`,
+		`ALTER TABLE bigram_stats ADD COLUMN last_reviewed DATETIME`,
+		`UPDATE key_stats SET last_reviewed = last_practiced`,
+		`UPDATE bigram_stats SET last_reviewed = last_practiced`,
+		`CREATE TABLE key_reviews (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			key TEXT NOT NULL,
+			reviewed_at DATETIME NOT NULL,
+			errors INTEGER NOT NULL,
+			successes INTEGER NOT NULL,
+			latency_ms REAL NOT NULL,
+			scheduler TEXT NOT NULL,
+			grade INTEGER NOT NULL,
+			applied INTEGER NOT NULL,
+			interval INTEGER NOT NULL,
+			repetitions INTEGER NOT NULL,
+			ease_factor REAL NOT NULL,
+			stability REAL NOT NULL,
+			difficulty REAL NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_key_reviews_key ON key_reviews(kind, key)`,
+	)},
+	{10, "create confusions", execStatements(`
+	CREATE TABLE confusions (
+		expected TEXT NOT NULL,
+		typed TEXT NOT NULL,
+		count INTEGER NOT NULL,
+		last_seen DATETIME NOT NULL,
+		PRIMARY KEY (expected, typed)
+	)`)},
+	{11, "add error classes to sessions", execStatements(
+		`ALTER TABLE sessions ADD COLUMN substitutions INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN insertions INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN omissions INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN transpositions INTEGER NOT NULL DEFAULT 0`,
+	)},
+	// Older sessions have no keystroke log to derive these from, so raw
+	// WPM falls back to the net figure.
+	{12, "add speed metrics to sessions", execStatements(
+		`ALTER TABLE sessions ADD COLUMN raw_wpm REAL NOT NULL DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN corrected_errors INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN consistency REAL NOT NULL DEFAULT 0`,
+		`UPDATE sessions SET raw_wpm = wpm`,
+	)},
+	{13, "add test mode to sessions", execStatements(
+		`ALTER TABLE sessions ADD COLUMN test_mode TEXT NOT NULL DEFAULT ''`,
+	)},
+	// There was no accuracy gate before, so every earlier session passed.
+	{14, "add passed to sessions", execStatements(
+		`ALTER TABLE sessions ADD COLUMN passed BOOLEAN NOT NULL DEFAULT 1`,
+	)},
+	{15, "create unlocked_keys", execStatements(`
+	CREATE TABLE unlocked_keys (
+		layout TEXT NOT NULL,
+		language TEXT NOT NULL,
+		key TEXT NOT NULL,
+		unlocked_at DATETIME NOT NULL,
+		PRIMARY KEY (layout, language, key)
+	)`)},
+	{16, "create file_progress", execStatements(`
+	CREATE TABLE file_progress (
+		path TEXT PRIMARY KEY,
+		hash TEXT NOT NULL,
+		chapter INTEGER NOT NULL DEFAULT 0,
+		offset INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME NOT NULL
+	)`)},
+	// Session times were written with the local UTC offset, which SQLite
+	// cannot compare; they are rewritten in UTC at a fixed width.
+	{17, "store session times in UTC", normaliseSessionTimes},
+}
+
+// runMigrations applies every migration newer than the recorded schema
+// version. All pending migrations run in one transaction, so a failure
+// leaves the database exactly as it was.
+func runMigrations(conn *sql.DB, ms []migration) error {
+	for i := 1; i < len(ms); i++ {
+		if ms[i].version <= ms[i-1].version {
+			return fmt.Errorf("stats: migration %d (%s) is out of order", ms[i].version, ms[i].name)
+		}
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("stats: failed to create schema_version table: %w", err)
+	}
+
+	current, err := schemaVersion(tx)
+	if err != nil {
+		return fmt.Errorf("stats: failed to read schema version: %w", err)
+	}
+
+	if len(ms) > 0 && current > ms[len(ms)-1].version {
+		return fmt.Errorf("stats: database schema version %d is newer than this build supports (%d)",
+			current, ms[len(ms)-1].version)
+	}
+
+	now := time.Now()
+	for _, m := range ms {
+		if m.version <= current {
+			continue
+		}
+		if err := m.up(tx); err != nil {
+			return fmt.Errorf("stats: migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		_, err := tx.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.version, m.name, now)
+		if err != nil {
+			return fmt.Errorf("stats: failed to record migration %d: %w", m.version, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func schemaVersion(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}) (int, error) {
+	var version int
+	err := q.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// SchemaVersion returns the highest migration applied to the database.
+func (db *DB) SchemaVersion() (int, error) {
+	return schemaVersion(db.conn)
+}
+
+func execStatements(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func normaliseSessionTimes(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, timestamp FROM sessions`)
+	if err != nil {
+		return err
+	}
+	times := make(map[int]time.Time)
+	for rows.Next() {
+		var id int
+		var t time.Time
+		if err := rows.Scan(&id, &t); err != nil {
+			rows.Close()
+			return err
+		}
+		times[id] = t
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, t := range times {
+		if _, err := tx.Exec(`UPDATE sessions SET timestamp = ? WHERE id = ?`, dbTime(t), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumns adds each "name TYPE ..." column definition that the table
+// does not have yet.
+func addColumns(table string, columns ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, column := range columns {
+			var name string
+			fmt.Sscan(column, &name)
+
+			var count int
+			err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, name).Scan(&count)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s`, table, column)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,196 @@
+package stats
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadFixture(t *testing.T, dbPath, fixture string) {
+	t.Helper()
+
+	script, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	conn, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec(string(script)); err != nil {
+		t.Fatalf("Failed to load fixture %s: %v", fixture, err)
+	}
+}
+
+func latestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func TestMigrationsFreshDatabase(t *testing.T) {
+	tmpDB := "/tmp/kata_test_migrations_fresh.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if version != latestVersion() {
+		t.Errorf("Expected schema version %d, got %d", latestVersion(), version)
+	}
+	db.Close()
+
+	// Reopening must not re-apply anything.
+	db, err = NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Reopening failed: %v", err)
+	}
+	defer db.Close()
+
+	var applied int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM schema_version`).Scan(&applied); err != nil {
+		t.Fatalf("Failed to count migrations: %v", err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("Expected %d recorded migrations, got %d", len(migrations), applied)
+	}
+}
+
+func TestMigrationsLegacyFixture(t *testing.T) {
+	tmpDB := "/tmp/kata_test_migrations_legacy.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	loadFixture(t, tmpDB, "testdata/v0_legacy.sql")
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("NewDB failed on legacy fixture: %v", err)
+	}
+	defer db.Close()
+
+	sessions, err := db.GetRecentSessions(10)
+	if err != nil {
+		t.Fatalf("GetRecentSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Text != "func main" {
+		t.Errorf("Legacy session was not preserved: %+v", sessions)
+	}
+	if len(sessions) == 1 && sessions[0].RawWPM != sessions[0].WPM {
+		t.Errorf("Expected legacy raw WPM to fall back to WPM, got %+v", sessions[0])
+	}
+	if len(sessions) == 1 && !sessions[0].Passed {
+		t.Error("Expected legacy sessions to count as passed")
+	}
+	if len(sessions) == 1 && !sessions[0].Timestamp.Equal(time.Date(2025, 1, 10, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected the legacy timestamp to be kept, got %v", sessions[0].Timestamp)
+	}
+	var stored string
+	if err := db.conn.QueryRow(`SELECT CAST(timestamp AS TEXT) FROM sessions`).Scan(&stored); err != nil {
+		t.Fatalf("Failed to read the stored timestamp: %v", err)
+	}
+	if stored != "2025-01-10 09:30:00.000000000+00:00" {
+		t.Errorf("Expected the timestamp rewritten in UTC, got %q", stored)
+	}
+
+	keyStats, err := db.GetAllKeyStats()
+	if err != nil {
+		t.Fatalf("GetAllKeyStats failed: %v", err)
+	}
+	if len(keyStats) != 1 || keyStats[0].Successes != 18 || keyStats[0].EaseFactor != 2.5 {
+		t.Errorf("Legacy key stats were not migrated: %+v", keyStats)
+	}
+}
+
+func TestMigrationFailureRollsBack(t *testing.T) {
+	tmpDB := "/tmp/kata_test_migrations_failure.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	loadFixture(t, tmpDB, "testdata/v0_legacy.sql")
+
+	conn, err := sql.Open("sqlite", tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %v", err)
+	}
+	defer conn.Close()
+
+	broken := append([]migration{}, migrations...)
+	broken = append(broken, migration{
+		version: latestVersion() + 1,
+		name:    "broken",
+		up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`CREATE TABLE half_done (id INTEGER)`); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		},
+	})
+
+	err = runMigrations(conn, broken)
+	if err == nil {
+		t.Fatal("Expected migration error")
+	}
+	if !strings.Contains(err.Error(), "broken") || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Error should name the failed migration and cause, got: %v", err)
+	}
+
+	var tables int
+	err = conn.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name IN ('schema_version', 'keystrokes', 'half_done')
+	`).Scan(&tables)
+	if err != nil {
+		t.Fatalf("Failed to inspect schema: %v", err)
+	}
+	if tables != 0 {
+		t.Errorf("Failed migration left %d new tables behind", tables)
+	}
+
+	var count int
+	conn.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('key_stats') WHERE name='interval'`).Scan(&count)
+	if count != 0 {
+		t.Error("Failed migration run should not have altered key_stats")
+	}
+}
+
+func TestMigrationsRejectNewerSchema(t *testing.T) {
+	tmpDB := "/tmp/kata_test_migrations_newer.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	_, err = db.conn.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, 'future', CURRENT_TIMESTAMP)`,
+		latestVersion()+10)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Failed to insert future version: %v", err)
+	}
+
+	if _, err := NewDB(tmpDB); err == nil {
+		t.Error("Expected NewDB to refuse a newer schema version")
+	}
+}
+
+func TestMigrationsOrdered(t *testing.T) {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version != migrations[i-1].version+1 {
+			t.Errorf("Migration %q has version %d, expected %d",
+				migrations[i].name, migrations[i].version, migrations[i-1].version+1)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type SessionOrder int
+
+const (
+	OrderByTime SessionOrder = iota
+	OrderByWPM
+	OrderByAccuracy
+)
+
+// SessionResult selects sessions by whether they passed the accuracy gate.
+type SessionResult int
+
+const (
+	ResultAny SessionResult = iota
+	ResultPassed
+	ResultFailed
+)
+
+// SessionFilter selects sessions for QuerySessions and AggregateSessions.
+// Zero values mean "no constraint".
+type SessionFilter struct {
+	From        time.Time // inclusive
+	To          time.Time // exclusive
+	Mode        string
+	Language    string
+	TestMode    string  // e.g. "time:30"
+	MinDuration float64 // seconds
+	Result      SessionResult
+
+	OrderBy   SessionOrder
+	Ascending bool
+	Limit     int
+}
+
+func (f SessionFilter) where() (string, []any) {
+	var clauses []string
+	var args []any
+
+	if f.Mode != "" {
+		clauses = append(clauses, "mode = ?")
+		args = append(args, f.Mode)
+	}
+	if f.Language != "" {
+		clauses = append(clauses, "language = ?")
+		args = append(args, f.Language)
+	}
+	if f.TestMode != "" {
+		clauses = append(clauses, "test_mode = ?")
+		args = append(args, f.TestMode)
+	}
+	if !f.From.IsZero() {
+		clauses = append(clauses, "timestamp >= ?")
+		args = append(args, dbTime(f.From))
+	}
+	if !f.To.IsZero() {
+		clauses = append(clauses, "timestamp < ?")
+		args = append(args, dbTime(f.To))
+	}
+	if f.MinDuration > 0 {
+		clauses = append(clauses, "duration >= ?")
+		args = append(args, f.MinDuration)
+	}
+	switch f.Result {
+	case ResultPassed:
+		clauses = append(clauses, "passed = 1")
+	case ResultFailed:
+		clauses = append(clauses, "passed = 0")
+	}
+
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(clauses, " AND "), args
+}
+
+func (f SessionFilter) orderBy() string {
+	column := "timestamp"
+	switch f.OrderBy {
+	case OrderByWPM:
+		column = "wpm"
+	case OrderByAccuracy:
+		column = "accuracy"
+	}
+
+	if f.Ascending {
+		return "ORDER BY " + column + " ASC, id ASC"
+	}
+	return "ORDER BY " + column + " DESC, id DESC"
+}
+
+// QuerySessions returns the sessions matching the filter.
+func (db *DB) QuerySessions(f SessionFilter) ([]Session, error) {
+	where, args := f.where()
+	query := fmt.Sprintf(`SELECT %s FROM sessions %s %s`, sessionColumns, where, f.orderBy())
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSessions(rows)
+}
+
+type GroupBy int
+
+const (
+	GroupNone GroupBy = iota
+	GroupDay
+	GroupWeek
+	GroupMode
+)
+
+// Aggregate summarises a group of sessions. Group is the day
+// ("2006-01-02"), ISO week ("2006-W02") or mode label, and empty for
+// GroupNone.
+type Aggregate struct {
+	Group         string
+	Count         int
+	Failed        int // sessions below the accuracy gate
+	MeanWPM       float64
+	MedianWPM     float64
+	P90WPM        float64
+	BestWPM       float64
+	MeanAccuracy  float64
+	TotalDuration float64 // seconds
+}
+
+// AggregateSessions summarises the filtered sessions, grouped as requested.
+// Day and week boundaries are taken in loc (time.Local when nil). Groups
+// are returned in chronological order, or by session count for GroupMode.
+// The filter's Limit and ordering are ignored.
+func (db *DB) AggregateSessions(f SessionFilter, group GroupBy, loc *time.Location) ([]Aggregate, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	f.Limit = 0
+	f.OrderBy = OrderByTime
+	f.Ascending = true
+	sessions, err := db.QuerySessions(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	groups := make(map[string][]Session)
+	for _, s := range sessions {
+		var key string
+		switch group {
+		case GroupDay:
+			key = s.Timestamp.In(loc).Format("2006-01-02")
+		case GroupWeek:
+			year, week := s.Timestamp.In(loc).ISOWeek()
+			key = fmt.Sprintf("%d-W%02d", year, week)
+		case GroupMode:
+			key = s.Label()
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], s)
+	}
+
+	if group == GroupMode {
+		sort.SliceStable(keys, func(i, j int) bool {
+			return len(groups[keys[i]]) > len(groups[keys[j]])
+		})
+	}
+
+	aggregates := make([]Aggregate, 0, len(keys))
+	for _, key := range keys {
+		aggregates = append(aggregates, summarizeSessions(key, groups[key]))
+	}
+
+	return aggregates, nil
+}
+
+func summarizeSessions(group string, sessions []Session) Aggregate {
+	agg := Aggregate{Group: group, Count: len(sessions)}
+
+	wpms := make([]float64, len(sessions))
+	for i, s := range sessions {
+		wpms[i] = s.WPM
+		agg.MeanWPM += s.WPM
+		agg.MeanAccuracy += s.Accuracy
+		agg.TotalDuration += s.Duration
+		if !s.Passed {
+			agg.Failed++
+		}
+		agg.BestWPM = math.Max(agg.BestWPM, s.WPM)
+	}
+	agg.MeanWPM /= float64(len(sessions))
+	agg.MeanAccuracy /= float64(len(sessions))
+
+	sort.Float64s(wpms)
+	agg.MedianWPM = median(wpms)
+	agg.P90WPM = percentile(wpms, 0.9)
+
+	return agg
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
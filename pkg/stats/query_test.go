@@ -0,0 +1,137 @@
+package stats
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func seedQuerySessions(t *testing.T, db *DB, base time.Time) {
+	t.Helper()
+
+	// Sessions saved with different UTC offsets must still sort and
+	// filter by instant.
+	west := time.FixedZone("UTC-8", -8*3600)
+	sessions := []Session{
+		{Text: "a", WPM: 40, Accuracy: 90, Duration: 30, Mode: "symbols", Language: "rust", Timestamp: base},
+		{Text: "b", WPM: 90, Accuracy: 98, Duration: 60, Mode: "keywords", Language: "english", Timestamp: base.Add(1 * time.Hour)},
+		{Text: "c", WPM: 80, Accuracy: 96, Duration: 5, Mode: "keywords", Language: "english", Timestamp: base.Add(24 * time.Hour).In(west)},
+		{Text: "d", WPM: 70, Accuracy: 94, Duration: 45, Mode: "keywords", Language: "english", Timestamp: base.Add(8 * 24 * time.Hour)},
+	}
+	for _, s := range sessions {
+		if err := db.SaveSession(s); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+}
+
+func TestQuerySessions(t *testing.T) {
+	tmpDB := "/tmp/kata_test_query_sessions.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	base := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	seedQuerySessions(t, db, base)
+
+	cases := []struct {
+		name   string
+		filter SessionFilter
+		texts  string
+	}{
+		{"all newest first", SessionFilter{}, "dcba"},
+		{"oldest first", SessionFilter{Ascending: true}, "abcd"},
+		{"by mode", SessionFilter{Mode: "keywords"}, "dcb"},
+		{"by language", SessionFilter{Language: "rust"}, "a"},
+		{"min duration", SessionFilter{MinDuration: 30}, "dba"},
+		{"date range", SessionFilter{From: base.Add(time.Hour), To: base.Add(48 * time.Hour)}, "cb"},
+		{"date range in another zone", SessionFilter{From: base.Add(2 * time.Hour).In(time.FixedZone("UTC+9", 9*3600))}, "dc"},
+		{"limit", SessionFilter{Limit: 2}, "dc"},
+		{"limit after range", SessionFilter{To: base.Add(48 * time.Hour), Limit: 2}, "cb"},
+		{"by wpm", SessionFilter{OrderBy: OrderByWPM}, "bcda"},
+		{"by accuracy asc", SessionFilter{OrderBy: OrderByAccuracy, Ascending: true, Limit: 2}, "ad"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions, err := db.QuerySessions(tc.filter)
+			if err != nil {
+				t.Fatalf("QuerySessions failed: %v", err)
+			}
+			got := ""
+			for _, s := range sessions {
+				got += s.Text
+			}
+			if got != tc.texts {
+				t.Errorf("Expected sessions %q, got %q", tc.texts, got)
+			}
+		})
+	}
+}
+
+func TestAggregateSessions(t *testing.T) {
+	tmpDB := "/tmp/kata_test_aggregate_sessions.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	base := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC) // a Monday
+	seedQuerySessions(t, db, base)
+
+	overall, err := db.AggregateSessions(SessionFilter{}, GroupNone, time.UTC)
+	if err != nil {
+		t.Fatalf("AggregateSessions failed: %v", err)
+	}
+	if len(overall) != 1 {
+		t.Fatalf("Expected a single group, got %d", len(overall))
+	}
+	a := overall[0]
+	if a.Count != 4 || a.MeanWPM != 70 || a.MedianWPM != 75 || a.P90WPM != 90 || a.BestWPM != 90 || a.TotalDuration != 140 {
+		t.Errorf("Unexpected overall aggregate: %+v", a)
+	}
+
+	days, err := db.AggregateSessions(SessionFilter{}, GroupDay, time.UTC)
+	if err != nil {
+		t.Fatalf("AggregateSessions by day failed: %v", err)
+	}
+	if len(days) != 3 || days[0].Group != "2025-03-03" || days[0].Count != 2 || days[2].Group != "2025-03-11" {
+		t.Errorf("Unexpected daily groups: %+v", days)
+	}
+
+	weeks, err := db.AggregateSessions(SessionFilter{Mode: "keywords"}, GroupWeek, time.UTC)
+	if err != nil {
+		t.Fatalf("AggregateSessions by week failed: %v", err)
+	}
+	if len(weeks) != 2 || weeks[0].Group != "2025-W10" || weeks[0].Count != 2 || weeks[1].Group != "2025-W11" {
+		t.Errorf("Unexpected weekly groups: %+v", weeks)
+	}
+
+	modes, err := db.AggregateSessions(SessionFilter{}, GroupMode, time.UTC)
+	if err != nil {
+		t.Fatalf("AggregateSessions by mode failed: %v", err)
+	}
+	if len(modes) != 2 || modes[0].Group != "keywords (english)" || modes[0].Count != 3 || modes[0].MeanWPM != 80 {
+		t.Errorf("Unexpected mode groups: %+v", modes)
+	}
+
+	// Day boundaries follow the requested time zone: 09:00 and 10:00 UTC
+	// on March 3rd fall on different days in UTC+14.
+	kiribati := time.FixedZone("UTC+14", 14*60*60)
+	days, err = db.AggregateSessions(SessionFilter{}, GroupDay, kiribati)
+	if err != nil {
+		t.Fatalf("AggregateSessions in other zone failed: %v", err)
+	}
+	if days[0].Group != "2025-03-03" || days[0].Count != 1 || days[1].Group != "2025-03-04" {
+		t.Errorf("Unexpected daily groups in UTC+14: %+v", days)
+	}
+}
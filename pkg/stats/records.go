@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// Text length buckets for personal bests; a 20-character drill and a
+// 600-character file are not comparable.
+const (
+	LengthShort  = "short"
+	LengthMedium = "medium"
+	LengthLong   = "long"
+)
+
+func LengthBucket(text string) string {
+	n := utf8.RuneCountInString(text)
+	switch {
+	case n < 100:
+		return LengthShort
+	case n < 300:
+		return LengthMedium
+	default:
+		return LengthLong
+	}
+}
+
+// recordLength is the length category of a session: its test shape for
+// timed and word-count tests, whose text length is arbitrary, otherwise
+// its length bucket.
+func recordLength(s Session) string {
+	if s.TestMode != "" {
+		return s.TestMode
+	}
+	return LengthBucket(s.Text)
+}
+
+// PersonalBest holds the records for one lesson type, language and
+// length bucket (or test shape).
+type PersonalBest struct {
+	Mode         string
+	Language     string
+	Length       string
+	Sessions     int
+	BestWPM      float64
+	BestWPMAt    time.Time
+	BestAccuracy float64
+}
+
+// RecordResult compares a finished session against the records that
+// stood before it.
+type RecordResult struct {
+	NewWPM           bool
+	PreviousWPM      float64
+	NewAccuracy      bool
+	PreviousAccuracy float64
+}
+
+// WPMDelta is how much the WPM record improved.
+func (r RecordResult) WPMDelta(wpm float64) float64 {
+	return wpm - r.PreviousWPM
+}
+
+func recordKey(mode, language, length string) [3]string {
+	return [3]string{mode, language, length}
+}
+
+// recordLengthSQL computes recordLength in SQL; length counts runes.
+const recordLengthSQL = `CASE
+		WHEN test_mode != '' THEN test_mode
+		WHEN length(text) < 100 THEN '` + LengthShort + `'
+		WHEN length(text) < 300 THEN '` + LengthMedium + `'
+		ELSE '` + LengthLong + `' END`
+
+// GetPersonalBests returns the records for every category that has been
+// passed, most practised first. Failed sessions do not count.
+func (db *DB) GetPersonalBests() ([]PersonalBest, error) {
+	rows, err := db.conn.Query(`
+	WITH passed AS (
+		SELECT id, mode, language, wpm, accuracy, timestamp, ` + recordLengthSQL + ` AS length
+		FROM sessions WHERE passed = 1
+	)
+	SELECT mode, language, length, COUNT(*), MAX(wpm), MAX(accuracy),
+		(SELECT CAST(timestamp AS TEXT) FROM passed b
+		WHERE b.mode = a.mode AND b.language = a.language AND b.length = a.length
+		ORDER BY wpm DESC, timestamp ASC, id ASC LIMIT 1)
+	FROM passed a
+	GROUP BY mode, language, length
+	ORDER BY COUNT(*) DESC, MIN(timestamp) ASC, MIN(id) ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []PersonalBest
+	for rows.Next() {
+		var pb PersonalBest
+		var at string
+		if err := rows.Scan(&pb.Mode, &pb.Language, &pb.Length, &pb.Sessions, &pb.BestWPM, &pb.BestAccuracy, &at); err != nil {
+			return nil, err
+		}
+		if t, err := time.Parse(timeLayout, at); err == nil {
+			pb.BestWPMAt = t.Local()
+		}
+		records = append(records, pb)
+	}
+
+	return records, rows.Err()
+}
+
+// CheckRecord reports whether session beats the records of its category.
+// It must be called before the session is saved. The first session of a
+// category sets the records without counting as a new best, and a failed
+// session never beats anything.
+func (db *DB) CheckRecord(session Session) (RecordResult, error) {
+	if !session.Passed {
+		return RecordResult{}, nil
+	}
+
+	var count int
+	var bestWPM, bestAccuracy float64
+	err := db.conn.QueryRow(`
+	SELECT COUNT(*), COALESCE(MAX(wpm), 0), COALESCE(MAX(accuracy), 0)
+	FROM sessions
+	WHERE passed = 1 AND mode = ? AND language = ? AND `+recordLengthSQL+` = ?
+	`, session.Mode, session.Language, recordLength(session)).Scan(&count, &bestWPM, &bestAccuracy)
+	if err != nil || count == 0 {
+		return RecordResult{}, err
+	}
+
+	return RecordResult{
+		NewWPM:           session.WPM > bestWPM,
+		PreviousWPM:      bestWPM,
+		NewAccuracy:      session.Accuracy > bestAccuracy,
+		PreviousAccuracy: bestAccuracy,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package stats
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLengthBucket(t *testing.T) {
+	cases := map[int]string{10: LengthShort, 99: LengthShort, 100: LengthMedium, 299: LengthMedium, 300: LengthLong}
+	for n, want := range cases {
+		if got := LengthBucket(strings.Repeat("é", n)); got != want {
+			t.Errorf("LengthBucket(%d runes) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestPersonalBests(t *testing.T) {
+	tmpDB := "/tmp/kata_test_records.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	short := "if err != nil"
+	long := strings.Repeat("func main() {} ", 30)
+
+	first := Session{Text: short, WPM: 50, Accuracy: 95, Mode: "code", Language: "go", Passed: true, Timestamp: now}
+	result, err := db.CheckRecord(first)
+	if err != nil {
+		t.Fatalf("CheckRecord failed: %v", err)
+	}
+	if result.NewWPM || result.NewAccuracy {
+		t.Error("The first session of a category should not be reported as a new best")
+	}
+
+	sessions := []Session{
+		first,
+		{Text: short, WPM: 60, Accuracy: 92, Mode: "code", Language: "go", Passed: true, Timestamp: now.Add(time.Minute)},
+		{Text: long, WPM: 80, Accuracy: 99, Mode: "code", Language: "go", Passed: true, Timestamp: now.Add(2 * time.Minute)},
+		{Text: short, WPM: 90, Accuracy: 100, Mode: "code", Language: "rust", Passed: true, Timestamp: now.Add(3 * time.Minute)},
+	}
+	for _, s := range sessions {
+		if err := db.SaveSession(s); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+
+	records, err := db.GetPersonalBests()
+	if err != nil {
+		t.Fatalf("GetPersonalBests failed: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 categories, got %d: %+v", len(records), records)
+	}
+	top := records[0]
+	if top.Language != "go" || top.Length != LengthShort || top.Sessions != 2 || top.BestWPM != 60 || top.BestAccuracy != 95 {
+		t.Errorf("Unexpected short Go record: %+v", top)
+	}
+
+	result, err = db.CheckRecord(Session{Text: short, WPM: 65.5, Accuracy: 94, Mode: "code", Language: "go", Passed: true})
+	if err != nil {
+		t.Fatalf("CheckRecord failed: %v", err)
+	}
+	if !result.NewWPM || result.PreviousWPM != 60 || result.WPMDelta(65.5) != 5.5 {
+		t.Errorf("Expected a new WPM record (+5.5), got %+v", result)
+	}
+	if result.NewAccuracy {
+		t.Error("94% should not beat the 95% accuracy record")
+	}
+}
+
+func TestPersonalBestsByTestMode(t *testing.T) {
+	tmpDB := "/tmp/kata_test_records_tests.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	sessions := []Session{
+		{Text: "the quick brown fox", WPM: 70, Mode: "keywords", Language: "english", TestMode: "time:30", Passed: true, Timestamp: now},
+		{Text: "the quick brown fox jumps", WPM: 50, Mode: "keywords", Language: "english", TestMode: "time:60", Passed: true, Timestamp: now},
+		{Text: "the lazy dog", WPM: 60, Mode: "keywords", Language: "english", Passed: true, Timestamp: now},
+	}
+	for _, s := range sessions {
+		if err := db.SaveSession(s); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+
+	records, err := db.GetPersonalBests()
+	if err != nil {
+		t.Fatalf("GetPersonalBests failed: %v", err)
+	}
+	lengths := make(map[string]float64)
+	for _, r := range records {
+		lengths[r.Length] = r.BestWPM
+	}
+	if len(lengths) != 3 || lengths["time:30"] != 70 || lengths["time:60"] != 50 || lengths[LengthShort] != 60 {
+		t.Errorf("Expected separate records per test shape, got %v", lengths)
+	}
+
+	timed, err := db.QuerySessions(SessionFilter{TestMode: "time:30"})
+	if err != nil || len(timed) != 1 || timed[0].WPM != 70 {
+		t.Errorf("Expected to filter by test mode, got %v (err %v)", timed, err)
+	}
+}
+
+func TestFailedSessionsSetNoRecords(t *testing.T) {
+	tmpDB := "/tmp/kata_test_records_failed.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	sessions := []Session{
+		{Text: "abc", WPM: 40, Accuracy: 97, Mode: "bigrams", Passed: true, Timestamp: now},
+		{Text: "abc", WPM: 90, Accuracy: 80, Mode: "bigrams", Passed: false, Timestamp: now.Add(time.Minute)},
+	}
+	for _, s := range sessions {
+		if err := db.SaveSession(s); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+
+	records, err := db.GetPersonalBests()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Expected 1 category, got %+v (err %v)", records, err)
+	}
+	if records[0].BestWPM != 40 || records[0].Sessions != 1 {
+		t.Errorf("Expected the failed session to be ignored, got %+v", records[0])
+	}
+
+	result, err := db.CheckRecord(Session{Text: "abc", WPM: 99, Accuracy: 90, Mode: "bigrams"})
+	if err != nil || result.NewWPM {
+		t.Errorf("Expected a failed session not to set a record, got %+v (err %v)", result, err)
+	}
+
+	failed, err := db.QuerySessions(SessionFilter{Result: ResultFailed})
+	if err != nil || len(failed) != 1 || failed[0].WPM != 90 {
+		t.Errorf("Expected to filter failed sessions, got %v (err %v)", failed, err)
+	}
+
+	overall, err := db.AggregateSessions(SessionFilter{}, GroupNone, nil)
+	if err != nil || len(overall) != 1 || overall[0].Count != 2 || overall[0].Failed != 1 {
+		t.Errorf("Expected 1 of 2 sessions to be counted as failed, got %+v (err %v)", overall, err)
+	}
+}
@@ -0,0 +1,75 @@
+package stats
+
+import "time"
+
+// ReviewWindow is the minimum time between two scheduling updates of the
+// same key. Sessions inside the window still add to the counts and are
+// logged, but do not move the due date, so intervals grow with elapsed
+// days rather than with the number of sessions.
+const ReviewWindow = 12 * time.Hour
+
+// KeyReview is one row of the review history: the evidence a session gave
+// about a key or bigram, the grade it earned, and the state afterwards.
+type KeyReview struct {
+	Kind       string // "key" or "bigram"
+	Key        string
+	ReviewedAt time.Time
+	Errors     int
+	Successes  int
+	LatencyMs  float64
+	Scheduler  string
+	Grade      int
+	Applied    bool // false when skipped inside the review window
+
+	Interval    int
+	Repetitions int
+	EaseFactor  float64
+	Stability   float64
+	Difficulty  float64
+}
+
+// GetKeyReviews returns the review history of a key ("key") or bigram
+// ("bigram"), oldest first.
+func (db *DB) GetKeyReviews(kind, key string) ([]KeyReview, error) {
+	rows, err := db.conn.Query(`
+	SELECT kind, key, reviewed_at, errors, successes, latency_ms, scheduler, grade, applied,
+		interval, repetitions, ease_factor, stability, difficulty
+	FROM key_reviews
+	WHERE kind = ? AND key = ?
+	ORDER BY id ASC
+	`, kind, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []KeyReview
+	for rows.Next() {
+		var r KeyReview
+		if err := rows.Scan(&r.Kind, &r.Key, &r.ReviewedAt, &r.Errors, &r.Successes, &r.LatencyMs,
+			&r.Scheduler, &r.Grade, &r.Applied,
+			&r.Interval, &r.Repetitions, &r.EaseFactor, &r.Stability, &r.Difficulty); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, r)
+	}
+
+	return reviews, rows.Err()
+}
+
+// addLatencies sets the mean session latency of every counted key that has
+// samples.
+func addLatencies(counts map[string]attemptCounts, samples map[string][]float64) {
+	for key, values := range samples {
+		c, ok := counts[key]
+		if !ok || len(values) == 0 {
+			continue
+		}
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		c.latencyMs = sum / float64(len(values))
+		counts[key] = c
+	}
+}
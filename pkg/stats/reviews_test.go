@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReviewWindow(t *testing.T) {
+	tmpDB := "/tmp/kata_test_review_window.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := db.UpdateKeyStats("aaaa", "aaaa"); err != nil {
+			t.Fatalf("UpdateKeyStats failed: %v", err)
+		}
+	}
+
+	keys, err := db.GetAllKeyStats()
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("Expected 1 key, got %v (err %v)", keys, err)
+	}
+	if keys[0].Successes != 12 || keys[0].Repetitions != 1 || keys[0].Interval != 1 {
+		t.Errorf("Expected counts to grow but only one review inside the window, got %+v", keys[0])
+	}
+
+	// Once the window has passed the next session is scheduled again.
+	past := time.Now().Add(-ReviewWindow - time.Minute)
+	if _, err := db.conn.Exec(`UPDATE key_stats SET last_reviewed = ?`, past); err != nil {
+		t.Fatalf("Failed to backdate review: %v", err)
+	}
+	if err := db.UpdateKeyStats("aaaa", "aaaa"); err != nil {
+		t.Fatalf("UpdateKeyStats failed: %v", err)
+	}
+
+	reviews, err := db.GetKeyReviews("key", "a")
+	if err != nil {
+		t.Fatalf("GetKeyReviews failed: %v", err)
+	}
+	if len(reviews) != 4 {
+		t.Fatalf("Expected 4 review rows, got %d", len(reviews))
+	}
+
+	applied := []bool{true, false, false, true}
+	for i, r := range reviews {
+		if r.Applied != applied[i] {
+			t.Errorf("Review %d: expected applied=%v, got %v", i, applied[i], r.Applied)
+		}
+		if r.Grade != 5 || r.Scheduler != "sm2" || r.Successes != 4 {
+			t.Errorf("Review %d: unexpected row %+v", i, r)
+		}
+	}
+	if reviews[3].Repetitions != 2 || reviews[3].Interval != 6 {
+		t.Errorf("Expected the second applied review to reach 6 days, got %+v", reviews[3])
+	}
+}
+
+func TestUpdateKeyStatsWithKeystrokes(t *testing.T) {
+	tmpDB := "/tmp/kata_test_review_latency.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	// "b" is typed correctly but 800ms after "a".
+	keystrokes := keystrokesAt(time.Now(), "ab", "ab", []int{0, 800})
+	if err := db.UpdateKeyStatsWithKeystrokes("ab", "ab", keystrokes); err != nil {
+		t.Fatalf("UpdateKeyStatsWithKeystrokes failed: %v", err)
+	}
+
+	reviews, err := db.GetKeyReviews("key", "b")
+	if err != nil || len(reviews) != 1 {
+		t.Fatalf("Expected 1 review for 'b', got %v (err %v)", reviews, err)
+	}
+	if reviews[0].LatencyMs != 800 || reviews[0].Grade != 4 {
+		t.Errorf("Expected a hesitant review graded 4, got %+v", reviews[0])
+	}
+}
+
+func TestRejectedKeystrokesCountAsErrors(t *testing.T) {
+	tmpDB := "/tmp/kata_test_review_rejected.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	// Under stop-on-letter the 'x' never reached the input.
+	keystrokes := []Keystroke{
+		{Position: 0, Expected: "a", Typed: "a", Kind: KeystrokeInsert, Timestamp: time.Now()},
+		{Position: 1, Expected: "b", Typed: "x", Kind: KeystrokeRejected, Timestamp: time.Now()},
+		{Position: 1, Expected: "b", Typed: "b", Kind: KeystrokeInsert, Timestamp: time.Now()},
+	}
+	if err := db.UpdateKeyStatsWithKeystrokes("ab", "ab", keystrokes); err != nil {
+		t.Fatalf("UpdateKeyStatsWithKeystrokes failed: %v", err)
+	}
+
+	reviews, err := db.GetKeyReviews("key", "b")
+	if err != nil || len(reviews) != 1 {
+		t.Fatalf("Expected 1 review for 'b', got %v (err %v)", reviews, err)
+	}
+	if reviews[0].Errors != 1 || reviews[0].Successes != 1 {
+		t.Errorf("Expected 1 error and 1 success for 'b', got %+v", reviews[0])
+	}
+}
@@ -0,0 +1,195 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Review is one session's evidence about a key or bigram.
+type Review struct {
+	Errors    int
+	Successes int
+	LatencyMs float64 // mean latency of the correct attempts, 0 when unknown
+	Time      time.Time
+}
+
+// Accuracy is the share of correct attempts in the review.
+func (r Review) Accuracy() float64 {
+	total := r.Errors + r.Successes
+	if total == 0 {
+		return 0
+	}
+	return float64(r.Successes) / float64(total)
+}
+
+// latencyPenalty is how many grades a correct but hesitant recall loses.
+func latencyPenalty(ms float64) int {
+	switch {
+	case ms >= 1000:
+		return 2
+	case ms >= 600:
+		return 1
+	}
+	return 0
+}
+
+// Scheduler decides when a key or bigram is next due. Grade rates one
+// session's review; Schedule applies it after the review has been added to
+// the lifetime counts of k, and must set k.Interval (in days) and
+// k.LastReviewed.
+type Scheduler interface {
+	Name() string
+	Grade(r Review) int
+	Schedule(k *KeyStat, r Review)
+}
+
+// NewScheduler returns the scheduler with the given config name. An empty
+// name selects SM-2.
+func NewScheduler(name string) (Scheduler, error) {
+	switch name {
+	case "", "sm2":
+		return SM2Scheduler{}, nil
+	case "fsrs":
+		return NewFSRSScheduler(), nil
+	}
+	return nil, fmt.Errorf("unknown scheduler %q (want sm2 or fsrs)", name)
+}
+
+// SM2Scheduler is classic SM-2 with a 0-5 quality taken from the
+// session's accuracy. Slow recalls lose up to two points but never drop
+// below a pass.
+type SM2Scheduler struct{}
+
+func (SM2Scheduler) Name() string { return "sm2" }
+
+func (SM2Scheduler) Grade(r Review) int {
+	quality := accuracyToQuality(r.Accuracy())
+	if quality >= 3 {
+		quality = max(3, quality-latencyPenalty(r.LatencyMs))
+	}
+	return quality
+}
+
+func (s SM2Scheduler) Schedule(k *KeyStat, r Review) {
+	k.UpdateSM2(s.Grade(r))
+	k.LastPracticed = r.Time
+	k.LastReviewed = r.Time
+}
+
+// FSRS grades.
+const (
+	gradeAgain = 1
+	gradeHard  = 2
+	gradeGood  = 3
+	gradeEasy  = 4
+)
+
+// fsrsWeights are the published FSRS v4 default parameters. The scheduler
+// implements v4 throughout: its power forgetting curve, its linear initial
+// difficulty and its mean reversion towards the initial difficulty of Good.
+var fsrsWeights = [17]float64{
+	0.4, 0.6, 2.4, 5.8, 4.93, 0.94, 0.86, 0.01, 1.49,
+	0.14, 0.94, 2.18, 0.05, 0.34, 1.26, 0.29, 2.61,
+}
+
+// FSRSScheduler models each key with a memory stability (days until
+// recall probability falls to 90%) and a difficulty between 1 and 10. It
+// is graded from the session's own accuracy, so a key with a long history
+// still reacts to today's performance.
+type FSRSScheduler struct {
+	// DesiredRetention is the recall probability at which a key becomes
+	// due again.
+	DesiredRetention float64
+}
+
+func NewFSRSScheduler() FSRSScheduler {
+	return FSRSScheduler{DesiredRetention: 0.9}
+}
+
+func (FSRSScheduler) Name() string { return "fsrs" }
+
+// Grade maps the accuracy of one review to an FSRS grade. Like SM-2, slow
+// recalls are marked down but still count as recalled.
+func (FSRSScheduler) Grade(r Review) int {
+	accuracy := r.Accuracy()
+	var grade int
+	switch {
+	case accuracy >= 0.98:
+		grade = gradeEasy
+	case accuracy >= 0.90:
+		grade = gradeGood
+	case accuracy >= 0.75:
+		grade = gradeHard
+	default:
+		return gradeAgain
+	}
+	return max(gradeHard, grade-latencyPenalty(r.LatencyMs))
+}
+
+// Retrievability is the probability that k is recalled at time t.
+func (FSRSScheduler) Retrievability(k KeyStat, t time.Time) float64 {
+	if k.Stability <= 0 {
+		return 0
+	}
+	elapsed := t.Sub(k.LastReviewed).Hours() / 24
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Pow(1+elapsed/(9*k.Stability), -1)
+}
+
+func (s FSRSScheduler) Schedule(k *KeyStat, r Review) {
+	w := fsrsWeights
+	grade := s.Grade(r)
+
+	if k.Stability <= 0 {
+		k.Stability = w[grade-1]
+		k.Difficulty = initialDifficulty(grade)
+	} else {
+		retrievability := s.Retrievability(*k, r.Time)
+
+		difficulty := k.Difficulty - w[6]*float64(grade-gradeGood)
+		k.Difficulty = clamp(w[7]*initialDifficulty(gradeGood)+(1-w[7])*difficulty, 1, 10)
+
+		if grade == gradeAgain {
+			k.Stability = w[11] * math.Pow(k.Difficulty, -w[12]) *
+				(math.Pow(k.Stability+1, w[13]) - 1) * math.Exp(w[14]*(1-retrievability))
+		} else {
+			factor := math.Exp(w[8]) * (11 - k.Difficulty) * math.Pow(k.Stability, -w[9]) *
+				(math.Exp(w[10]*(1-retrievability)) - 1)
+			if grade == gradeHard {
+				factor *= w[15]
+			}
+			if grade == gradeEasy {
+				factor *= w[16]
+			}
+			k.Stability *= 1 + factor
+		}
+	}
+
+	if grade == gradeAgain {
+		k.Repetitions = 0
+	} else {
+		k.Repetitions++
+	}
+
+	retention := s.DesiredRetention
+	if retention <= 0 || retention >= 1 {
+		retention = 0.9
+	}
+	interval := int(math.Round(9 * k.Stability * (1/retention - 1)))
+	if interval < 1 {
+		interval = 1
+	}
+	k.Interval = interval
+	k.LastReviewed = r.Time
+}
+
+func initialDifficulty(grade int) float64 {
+	return clamp(fsrsWeights[4]-float64(grade-gradeGood)*fsrsWeights[5], 1, 10)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
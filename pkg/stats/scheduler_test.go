@@ -0,0 +1,230 @@
+package stats
+
+import (
+	"database/sql"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewScheduler(t *testing.T) {
+	for name, want := range map[string]string{"": "sm2", "sm2": "sm2", "fsrs": "fsrs"} {
+		s, err := NewScheduler(name)
+		if err != nil {
+			t.Fatalf("NewScheduler(%q) failed: %v", name, err)
+		}
+		if s.Name() != want {
+			t.Errorf("NewScheduler(%q) = %s, want %s", name, s.Name(), want)
+		}
+	}
+
+	if _, err := NewScheduler("leitner"); err == nil {
+		t.Error("Expected an error for an unknown scheduler")
+	}
+}
+
+func TestSM2SchedulerGradesTheSession(t *testing.T) {
+	now := time.Now()
+	k := KeyStat{Key: "a", Errors: 1, Successes: 99, EaseFactor: 2.5, Interval: 6, Repetitions: 2}
+
+	// A bad session lapses a key even with a long, clean history.
+	k.Errors += 5
+	SM2Scheduler{}.Schedule(&k, Review{Errors: 5, Successes: 0, Time: now})
+
+	if k.Repetitions != 0 || k.Interval != 1 {
+		t.Errorf("Expected the key to lapse, got %+v", k)
+	}
+	if !k.LastReviewed.Equal(now) {
+		t.Errorf("Expected LastReviewed %v, got %v", now, k.LastReviewed)
+	}
+}
+
+func TestSchedulerLatencyPenalty(t *testing.T) {
+	cases := []struct {
+		review Review
+		sm2    int
+		fsrs   int
+	}{
+		{Review{Successes: 20}, 5, gradeEasy},
+		{Review{Successes: 20, LatencyMs: 250}, 5, gradeEasy},
+		{Review{Successes: 20, LatencyMs: 700}, 4, gradeGood},
+		{Review{Successes: 20, LatencyMs: 1500}, 3, gradeHard},
+		{Review{Successes: 15, Errors: 5, LatencyMs: 1500}, 3, gradeHard},
+		{Review{Successes: 1, Errors: 3, LatencyMs: 100}, 0, gradeAgain},
+	}
+
+	for _, tc := range cases {
+		if got := (SM2Scheduler{}).Grade(tc.review); got != tc.sm2 {
+			t.Errorf("SM-2 grade for %+v: expected %d, got %d", tc.review, tc.sm2, got)
+		}
+		if got := NewFSRSScheduler().Grade(tc.review); got != tc.fsrs {
+			t.Errorf("FSRS grade for %+v: expected %d, got %d", tc.review, tc.fsrs, got)
+		}
+	}
+}
+
+func TestFSRSScheduler(t *testing.T) {
+	s := NewFSRSScheduler()
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	k := KeyStat{Key: "{", EaseFactor: 2.5}
+	s.Schedule(&k, Review{Successes: 19, Errors: 1, Time: start})
+	if k.Stability != fsrsWeights[2] || k.Interval != 2 {
+		t.Fatalf("Expected a new key graded good to start at S=%.1f, 2 days; got S=%.2f, %d days",
+			fsrsWeights[2], k.Stability, k.Interval)
+	}
+	if k.Difficulty != fsrsWeights[4] {
+		t.Errorf("Expected initial difficulty %.2f, got %.2f", fsrsWeights[4], k.Difficulty)
+	}
+
+	due := start.AddDate(0, 0, k.Interval)
+	if r := s.Retrievability(k, due); r < 0.88 || r > 0.92 {
+		t.Errorf("Expected ~90%% recall probability when due, got %.3f", r)
+	}
+
+	// A successful review when due increases stability.
+	before := k
+	s.Schedule(&k, Review{Successes: 20, Time: due})
+	if k.Stability <= before.Stability || k.Interval <= before.Interval {
+		t.Errorf("Expected stability to grow after an easy review, got %+v", k)
+	}
+	if k.Difficulty >= before.Difficulty {
+		t.Errorf("Expected difficulty to drop after an easy review, got %.2f", k.Difficulty)
+	}
+
+	// A failed session lapses the key regardless of its history.
+	k.Errors, k.Successes = 1, 500
+	before = k
+	s.Schedule(&k, Review{Errors: 8, Successes: 12, Time: due.AddDate(0, 0, 1)})
+	if k.Stability >= before.Stability || k.Interval >= before.Interval || k.Repetitions != 0 {
+		t.Errorf("Expected a lapse after a bad session, got %+v", k)
+	}
+	if k.Difficulty <= before.Difficulty {
+		t.Errorf("Expected difficulty to rise after a lapse, got %.2f", k.Difficulty)
+	}
+}
+
+// TestFSRSReferenceIntervals checks the intervals FSRS v4 gives with its
+// default parameters at 90% retention, worked out by hand from the v4
+// formulas.
+func TestFSRSReferenceIntervals(t *testing.T) {
+	s := NewFSRSScheduler()
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	again := Review{Successes: 1, Errors: 3}
+	hard := Review{Successes: 16, Errors: 4}
+	good := Review{Successes: 19, Errors: 1}
+	easy := Review{Successes: 20}
+
+	for _, tc := range []struct {
+		review    Review
+		stability float64
+		interval  int
+	}{
+		{again, 0.4, 1},
+		{hard, 0.6, 1},
+		{good, 2.4, 2},
+		{easy, 5.8, 6},
+	} {
+		k := KeyStat{}
+		tc.review.Time = start
+		s.Schedule(&k, tc.review)
+		if k.Stability != tc.stability || k.Interval != tc.interval {
+			t.Errorf("First review %+v: expected S=%.1f, %d days; got S=%.4f, %d days",
+				tc.review, tc.stability, tc.interval, k.Stability, k.Interval)
+		}
+	}
+
+	// Each review falls on the day the key is due.
+	k := KeyStat{}
+	now := start
+	for i, tc := range []struct {
+		review     Review
+		stability  float64
+		difficulty float64
+		interval   int
+	}{
+		{good, 2.4, 4.93, 2},
+		{good, 7.1416, 4.93, 7},
+		{good, 21.2685, 4.93, 21},
+		{good, 57.6295, 4.93, 58},
+		{again, 6.7349, 6.6328, 7},
+		{good, 16.9878, 6.6158, 17},
+	} {
+		tc.review.Time = now
+		s.Schedule(&k, tc.review)
+		if math.Abs(k.Stability-tc.stability) > 1e-3 || math.Abs(k.Difficulty-tc.difficulty) > 1e-3 || k.Interval != tc.interval {
+			t.Errorf("Review %d: expected S=%.4f D=%.4f, %d days; got S=%.4f D=%.4f, %d days",
+				i+1, tc.stability, tc.difficulty, tc.interval, k.Stability, k.Difficulty, k.Interval)
+		}
+		now = now.AddDate(0, 0, k.Interval)
+	}
+}
+
+func TestFSRSSchedulerInDB(t *testing.T) {
+	tmpDB := "/tmp/kata_test_fsrs.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	db.SetScheduler(NewFSRSScheduler())
+	if err := db.UpdateKeyStats("aaaa", "aaaa"); err != nil {
+		t.Fatalf("UpdateKeyStats failed: %v", err)
+	}
+
+	keys, err := db.GetAllKeyStats()
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("Expected 1 key, got %v (err %v)", keys, err)
+	}
+	if keys[0].Stability != fsrsWeights[3] || keys[0].Successes != 4 {
+		t.Errorf("Expected a perfect first review to be graded easy, got %+v", keys[0])
+	}
+}
+
+func TestMigrationSeedsFSRSState(t *testing.T) {
+	tmpDB := "/tmp/kata_test_migrations_fsrs.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	conn, err := sql.Open("sqlite", tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %v", err)
+	}
+	if err := runMigrations(conn, migrations[:7]); err != nil {
+		t.Fatalf("Failed to migrate to version 7: %v", err)
+	}
+	_, err = conn.Exec(`INSERT INTO key_stats (key, errors, successes, interval, repetitions, ease_factor)
+		VALUES ('p', 3, 30, 6, 2, 1.9), ('q', 0, 2, 0, 0, 2.5)`)
+	if err != nil {
+		t.Fatalf("Failed to seed key_stats: %v", err)
+	}
+	conn.Close()
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	defer db.Close()
+
+	keys, err := db.GetAllKeyStats()
+	if err != nil {
+		t.Fatalf("GetAllKeyStats failed: %v", err)
+	}
+	for _, k := range keys {
+		switch k.Key {
+		case "p":
+			if k.Stability != 6 || k.Difficulty < 7.49 || k.Difficulty > 7.51 {
+				t.Errorf("Expected p seeded with S=6, D=7.5; got S=%.2f, D=%.2f", k.Stability, k.Difficulty)
+			}
+		case "q":
+			if k.Stability != 0 || k.Difficulty != 0 {
+				t.Errorf("Expected unscheduled q to start fresh, got %+v", k)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+// timeLayout is how session times are stored: in UTC and at a fixed
+// width, so SQLite can compare and sort them as text.
+const timeLayout = "2006-01-02 15:04:05.000000000-07:00"
+
+func dbTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+const sessionColumns = `id, text, wpm, accuracy, duration, error_count, timestamp,
+	mode, language, source, source_hash, zen_mode, rules,
+	substitutions, insertions, omissions, transpositions,
+	raw_wpm, corrected_errors, consistency, test_mode, passed`
+
+func scanSessions(rows *sql.Rows) ([]Session, error) {
+	var sessions []Session
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.Text, &s.WPM, &s.Accuracy, &s.Duration, &s.ErrorCount, &s.Timestamp,
+			&s.Mode, &s.Language, &s.Source, &s.SourceHash, &s.ZenMode, &s.Rules,
+			&s.Substitutions, &s.Insertions, &s.Omissions, &s.Transpositions,
+			&s.RawWPM, &s.CorrectedErrors, &s.Consistency, &s.TestMode, &s.Passed); err != nil {
+			return nil, err
+		}
+		s.Timestamp = s.Timestamp.Local()
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+func insertSession(tx *sql.Tx, session Session) (int64, error) {
+	res, err := tx.Exec(`
+	INSERT INTO sessions (text, wpm, accuracy, duration, error_count, timestamp,
+		mode, language, source, source_hash, zen_mode, rules,
+		substitutions, insertions, omissions, transpositions,
+		raw_wpm, corrected_errors, consistency, test_mode, passed)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, session.Text, session.WPM, session.Accuracy, session.Duration, session.ErrorCount, dbTime(session.Timestamp),
+		session.Mode, session.Language, session.Source, session.SourceHash, session.ZenMode, session.Rules,
+		session.Substitutions, session.Insertions, session.Omissions, session.Transpositions,
+		session.RawWPM, session.CorrectedErrors, session.Consistency, session.TestMode, session.Passed)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// HashText returns a short, stable fingerprint of a lesson's text, used to
+// recognise the same source file across sessions.
+func HashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ModeLabel names a lesson type and language, e.g. "code (rust)".
+// Sessions recorded before metadata was tracked have neither.
+func ModeLabel(mode, language string) string {
+	if mode == "" {
+		return "untagged"
+	}
+	if language == "" {
+		return mode
+	}
+	return mode + " (" + language + ")"
+}
+
+// Label is ModeLabel plus the test shape, e.g. "keywords (go) time:30".
+// Only sessions with the same label are comparable.
+func (s Session) Label() string {
+	label := ModeLabel(s.Mode, s.Language)
+	if s.TestMode != "" {
+		label += " " + s.TestMode
+	}
+	return label
+}
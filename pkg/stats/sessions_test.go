@@ -0,0 +1,79 @@
+package stats
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSessionMetadata(t *testing.T) {
+	tmpDB := "/tmp/kata_test_session_metadata.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	session := Session{
+		Text:       "fn main() {}",
+		WPM:        38,
+		Accuracy:   97,
+		Duration:   10,
+		Timestamp:  time.Now(),
+		Mode:       "file",
+		Language:   "rust",
+		Source:     "/tmp/main.rs",
+		SourceHash: HashText("fn main() {}"),
+		ZenMode:    true,
+		Rules:      "standard",
+
+		Substitutions:  2,
+		Omissions:      1,
+		Transpositions: 1,
+
+		RawWPM:          45,
+		CorrectedErrors: 4,
+		Consistency:     81.5,
+	}
+	if err := db.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	sessions, err := db.GetRecentSessions(1)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("GetRecentSessions failed: %v", err)
+	}
+
+	got := sessions[0]
+	if got.Mode != "file" || got.Language != "rust" || got.Source != "/tmp/main.rs" ||
+		got.SourceHash != session.SourceHash || !got.ZenMode || got.Rules != "standard" {
+		t.Errorf("Metadata did not round trip: %+v", got)
+	}
+	if got.Substitutions != 2 || got.Insertions != 0 || got.Omissions != 1 || got.Transpositions != 1 {
+		t.Errorf("Error classes did not round trip: %+v", got)
+	}
+	if got.RawWPM != 45 || got.CorrectedErrors != 4 || got.Consistency != 81.5 {
+		t.Errorf("Speed metrics did not round trip: %+v", got)
+	}
+}
+
+func TestModeLabel(t *testing.T) {
+	cases := map[[2]string]string{
+		{"", ""}:         "untagged",
+		{"code", ""}:     "code",
+		{"code", "rust"}: "code (rust)",
+	}
+	for in, want := range cases {
+		if got := ModeLabel(in[0], in[1]); got != want {
+			t.Errorf("ModeLabel(%q, %q) = %q, want %q", in[0], in[1], got, want)
+		}
+	}
+
+	timed := Session{Mode: "keywords", Language: "go", TestMode: "time:30"}
+	if got := timed.Label(); got != "keywords (go) time:30" {
+		t.Errorf("Unexpected label for a timed session: %q", got)
+	}
+}
@@ -2,19 +2,47 @@ package stats
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	_ "modernc.org/sqlite"
+
+	"kata/pkg/align"
 )
 
 type Session struct {
 	ID         int
 	Text       string
-	WPM        float64
+	WPM        float64 // net WPM
 	Accuracy   float64
 	Duration   float64
-	ErrorCount int
+	ErrorCount int // uncorrected errors
 	Timestamp  time.Time
+
+	RawWPM          float64 // every rune typed, including deleted ones
+	CorrectedErrors int
+	Consistency     float64 // 0-100, evenness of the typing rhythm
+
+	// Metadata describing what was practised, so sessions of different
+	// kinds are not averaged together.
+	Mode       string // lesson type, e.g. "bigrams", "code", "file"
+	Language   string
+	Source     string // file path for file-based lessons
+	SourceHash string
+	ZenMode    bool
+	Rules      string // engine rules in effect
+	TestMode   string // "time:30", "words:25", or empty for a plain lesson
+
+	// Passed is false when the session fell below the accuracy threshold
+	// or was ended by sudden death. Failed sessions set no records.
+	Passed bool
+
+	// ErrorCount broken down by kind of mistake, from aligning the final
+	// input against the text.
+	Substitutions  int
+	Insertions     int
+	Omissions      int
+	Transpositions int
 }
 
 type KeyStat struct {
@@ -22,9 +50,14 @@ type KeyStat struct {
 	Errors        int
 	Successes     int
 	LastPracticed time.Time
+	LastReviewed  time.Time // when the scheduler last ran; due dates count from here
 	Interval      int
 	Repetitions   int
 	EaseFactor    float64
+
+	// FSRS memory state; zero until the key is first scheduled by FSRS.
+	Stability  float64
+	Difficulty float64
 }
 
 func (k *KeyStat) UpdateSM2(quality int) {
@@ -60,10 +93,12 @@ func (k *KeyStat) UpdateSM2(quality int) {
 type ErrorAnalysis struct {
 	CharErrors   map[string]int // individual characters that were typed wrong
 	BigramErrors map[string]int // two-character sequences that were typed wrong
+	Counts       align.Counts   // mistakes by kind
 }
 
 type DB struct {
-	conn *sql.DB
+	conn      *sql.DB
+	scheduler Scheduler
 }
 
 func NewDB(dbPath string) (*DB, error) {
@@ -76,126 +111,28 @@ func NewDB(dbPath string) (*DB, error) {
 		return nil, err
 	}
 
-	db := &DB{conn: conn}
-	if err := db.createTables(); err != nil {
+	db := &DB{conn: conn, scheduler: SM2Scheduler{}}
+	if err := runMigrations(conn, migrations); err != nil {
+		conn.Close()
 		return nil, err
 	}
 
 	return db, nil
 }
 
-func (db *DB) createTables() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		text TEXT NOT NULL,
-		wpm REAL NOT NULL,
-		accuracy REAL NOT NULL,
-		duration REAL NOT NULL,
-		error_count INTEGER NOT NULL,
-		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS key_stats (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		key TEXT NOT NULL,
-		errors INTEGER DEFAULT 0,
-		successes INTEGER DEFAULT 0,
-		last_practiced DATETIME DEFAULT CURRENT_TIMESTAMP,
-		interval INTEGER DEFAULT 0,
-		repetitions INTEGER DEFAULT 0,
-		ease_factor REAL DEFAULT 2.5,
-		UNIQUE(key)
-	);
-	`
-	_, err := db.conn.Exec(query)
-	if err != nil {
-		return err
-	}
-
-	if err := db.createIndexes(); err != nil {
-		return err
-	}
-
-	return db.migrateSchema()
-}
-
-func (db *DB) createIndexes() error {
-	indexes := []string{
-		`CREATE INDEX IF NOT EXISTS idx_sessions_timestamp ON sessions(timestamp DESC)`,
-		`CREATE INDEX IF NOT EXISTS idx_key_stats_last_practiced ON key_stats(last_practiced ASC)`,
-		`CREATE INDEX IF NOT EXISTS idx_key_stats_attempts ON key_stats((errors + successes) DESC)`,
-	}
-
-	for _, index := range indexes {
-		if _, err := db.conn.Exec(index); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func (db *DB) migrateSchema() error {
-	var count int
-	err := db.conn.QueryRow(`
-		SELECT COUNT(*) FROM pragma_table_info('key_stats') 
-		WHERE name='interval'
-	`).Scan(&count)
-	if err != nil {
-		return err
-	}
-
-	if count == 0 {
-		migrations := []string{
-			`ALTER TABLE key_stats ADD COLUMN interval INTEGER DEFAULT 0`,
-			`ALTER TABLE key_stats ADD COLUMN repetitions INTEGER DEFAULT 0`,
-			`ALTER TABLE key_stats ADD COLUMN ease_factor REAL DEFAULT 2.5`,
-		}
-
-		for _, migration := range migrations {
-			if _, err := db.conn.Exec(migration); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
+// SetScheduler chooses how keys and bigrams are rescheduled after a
+// session. The default is SM-2.
+func (db *DB) SetScheduler(s Scheduler) {
+	db.scheduler = s
 }
 
 func (db *DB) SaveSession(session Session) error {
-	query := `
-	INSERT INTO sessions (text, wpm, accuracy, duration, error_count, timestamp)
-	VALUES (?, ?, ?, ?, ?, ?)
-	`
-	_, err := db.conn.Exec(query, session.Text, session.WPM, session.Accuracy,
-		session.Duration, session.ErrorCount, session.Timestamp)
+	_, err := db.SaveSessionWithKeystrokes(session, nil)
 	return err
 }
 
 func (db *DB) GetRecentSessions(limit int) ([]Session, error) {
-	query := `
-	SELECT id, text, wpm, accuracy, duration, error_count, timestamp
-	FROM sessions
-	ORDER BY timestamp DESC
-	LIMIT ?
-	`
-	rows, err := db.conn.Query(query, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var sessions []Session
-	for rows.Next() {
-		var s Session
-		if err := rows.Scan(&s.ID, &s.Text, &s.WPM, &s.Accuracy, &s.Duration, &s.ErrorCount, &s.Timestamp); err != nil {
-			return nil, err
-		}
-		sessions = append(sessions, s)
-	}
-
-	return sessions, nil
+	return db.QuerySessions(SessionFilter{Limit: limit})
 }
 
 func (db *DB) GetAverageWPM() (float64, error) {
@@ -209,28 +146,29 @@ func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
+// AnalyzeErrors aligns input against target and attributes every
+// substituted, omitted or transposed rune to the key that was expected.
+// Extra typed runes count towards Counts but belong to no key.
 func AnalyzeErrors(target, input string) ErrorAnalysis {
 	analysis := ErrorAnalysis{
 		CharErrors:   make(map[string]int),
 		BigramErrors: make(map[string]int),
 	}
 
-	minLen := len(input)
-	if minLen > len(target) {
-		minLen = len(target)
-	}
-
-	for i := 0; i < minLen; i++ {
-		targetChar := string(target[i])
-		inputChar := string(input[i])
+	targetRunes := []rune(target)
+	edits := align.Align(targetRunes, []rune(input))
+	analysis.Counts = align.Summarize(edits)
 
-		if targetChar != inputChar {
-			analysis.CharErrors[targetChar]++
+	for i, outcome := range align.Outcomes(edits, len(targetRunes)) {
+		if outcome != align.Wrong {
+			continue
+		}
+		targetChar := string(targetRunes[i])
+		analysis.CharErrors[targetChar]++
 
-			if i > 0 {
-				bigram := string(target[i-1]) + targetChar
-				analysis.BigramErrors[bigram]++
-			}
+		if i > 0 {
+			bigram := string(targetRunes[i-1]) + targetChar
+			analysis.BigramErrors[bigram]++
 		}
 	}
 
@@ -254,25 +192,33 @@ func accuracyToQuality(accuracy float64) int {
 	}
 }
 
+type attemptCounts struct {
+	errors    int
+	successes int
+	latencyMs float64 // mean latency this session, 0 when unknown
+}
+
 func (db *DB) UpdateKeyStats(target, input string) error {
-	minLen := len([]rune(input))
+	return db.UpdateKeyStatsWithKeystrokes(target, input, nil)
+}
+
+// UpdateKeyStatsWithKeystrokes is UpdateKeyStats with the session's
+// keystroke log, whose latencies are used to grade slow keys down.
+func (db *DB) UpdateKeyStatsWithKeystrokes(target, input string, keystrokes []Keystroke) error {
 	targetRunes := []rune(target)
-	inputRunes := []rune(input)
+	outcomes := align.Outcomes(align.Align(targetRunes, []rune(input)), len(targetRunes))
 
-	if minLen > len(targetRunes) {
-		minLen = len(targetRunes)
-	}
+	charStats := make(map[string]attemptCounts)
 
-	charStats := make(map[string]struct {
-		errors    int
-		successes int
-	})
+	for i, outcome := range outcomes {
+		if outcome == align.Untyped {
+			continue
+		}
 
-	for i := 0; i < minLen; i++ {
 		key := string(targetRunes[i])
 		stats := charStats[key]
 
-		if targetRunes[i] == inputRunes[i] {
+		if outcome == align.Correct {
 			stats.successes++
 		} else {
 			stats.errors++
@@ -281,62 +227,109 @@ func (db *DB) UpdateKeyStats(target, input string) error {
 		charStats[key] = stats
 	}
 
+	// A key held back by a strictness rule never reaches the input, but
+	// it was still missed.
+	for _, k := range keystrokes {
+		if k.Kind == KeystrokeRejected && k.Expected != "" {
+			stats := charStats[k.Expected]
+			stats.errors++
+			charStats[k.Expected] = stats
+		}
+	}
+
+	latencies, _ := LatencySamples(keystrokes)
+	addLatencies(charStats, latencies)
+
+	return db.applySRSUpdates(keyTable, charStats)
+}
+
+// srsTable describes one of the tables holding SRS state.
+type srsTable struct {
+	name   string
+	column string
+	kind   string // recorded in key_reviews
+}
+
+var (
+	keyTable    = srsTable{"key_stats", "key", "key"}
+	bigramTable = srsTable{"bigram_stats", "bigram", "bigram"}
+)
+
+// applySRSUpdates adds the attempt counts to an SRS table and grades every
+// touched row on this session's performance. A row is rescheduled at most
+// once per ReviewWindow; every review is logged in key_reviews either way.
+func (db *DB) applySRSUpdates(table srsTable, counts map[string]attemptCounts) error {
 	tx, err := db.conn.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	query := `
-	INSERT INTO key_stats (key, errors, successes, last_practiced)
-	VALUES (?, ?, ?, ?)
-	ON CONFLICT(key) DO UPDATE SET
-		errors = errors + ?,
-		successes = successes + ?,
-		last_practiced = ?
-	`
+	query := fmt.Sprintf(`
+	INSERT INTO %[1]s (%[2]s, errors, successes, last_practiced, last_reviewed, interval, repetitions, ease_factor, stability, difficulty)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(%[2]s) DO UPDATE SET
+		errors = excluded.errors,
+		successes = excluded.successes,
+		last_practiced = excluded.last_practiced,
+		last_reviewed = excluded.last_reviewed,
+		interval = excluded.interval,
+		repetitions = excluded.repetitions,
+		ease_factor = excluded.ease_factor,
+		stability = excluded.stability,
+		difficulty = excluded.difficulty
+	`, table.name, table.column)
 	stmt, err := tx.Prepare(query)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
+	history, err := tx.Prepare(`
+	INSERT INTO key_reviews (kind, key, reviewed_at, errors, successes, latency_ms, scheduler, grade, applied,
+		interval, repetitions, ease_factor, stability, difficulty)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
 	now := time.Now()
-	for key, stats := range charStats {
-		var existingKey KeyStat
-		err := tx.QueryRow(`
-			SELECT key, errors, successes, last_practiced, interval, repetitions, ease_factor
-			FROM key_stats WHERE key = ?
-		`, key).Scan(&existingKey.Key, &existingKey.Errors, &existingKey.Successes,
-			&existingKey.LastPracticed, &existingKey.Interval, &existingKey.Repetitions, &existingKey.EaseFactor)
+	for key, stats := range counts {
+		var k KeyStat
+		row := tx.QueryRow(fmt.Sprintf(`
+			SELECT %[2]s, %[3]s FROM %[1]s WHERE %[2]s = ?
+		`, table.name, table.column, keyStatColumns), key)
+		err := scanKeyStat(row, &k)
 
 		if err != nil && err != sql.ErrNoRows {
 			return err
 		}
 
-		newErrors := existingKey.Errors + stats.errors
-		newSuccesses := existingKey.Successes + stats.successes
-		totalAttempts := newErrors + newSuccesses
-		accuracy := 0.0
-		if totalAttempts > 0 {
-			accuracy = float64(newSuccesses) / float64(totalAttempts)
+		if err == sql.ErrNoRows {
+			k = KeyStat{Key: key, EaseFactor: 2.5}
 		}
 
-		quality := accuracyToQuality(accuracy)
-		existingKey.Errors = newErrors
-		existingKey.Successes = newSuccesses
-		existingKey.UpdateSM2(quality)
+		review := Review{Errors: stats.errors, Successes: stats.successes, LatencyMs: stats.latencyMs, Time: now}
+		applied := k.LastReviewed.IsZero() || now.Sub(k.LastReviewed) >= ReviewWindow
+
+		k.Errors += stats.errors
+		k.Successes += stats.successes
+		k.LastPracticed = now
+		if applied {
+			db.scheduler.Schedule(&k, review)
+		}
 
-		_, err = stmt.Exec(key, stats.errors, stats.successes, now, stats.errors, stats.successes, now)
+		_, err = stmt.Exec(key, k.Errors, k.Successes, k.LastPracticed, k.LastReviewed,
+			k.Interval, k.Repetitions, k.EaseFactor, k.Stability, k.Difficulty)
 		if err != nil {
 			return err
 		}
 
-		_, err = tx.Exec(`
-			UPDATE key_stats 
-			SET interval = ?, repetitions = ?, ease_factor = ?
-			WHERE key = ?
-		`, existingKey.Interval, existingKey.Repetitions, existingKey.EaseFactor, key)
+		_, err = history.Exec(table.kind, key, now, stats.errors, stats.successes, stats.latencyMs,
+			db.scheduler.Name(), db.scheduler.Grade(review), applied,
+			k.Interval, k.Repetitions, k.EaseFactor, k.Stability, k.Difficulty)
 		if err != nil {
 			return err
 		}
@@ -361,7 +354,7 @@ func (db *DB) upsertKeyStats(key string, errors, successes int) error {
 
 func (db *DB) GetWeakestKeys(limit int) ([]KeyStat, error) {
 	query := `
-	SELECT key, errors, successes, last_practiced, interval, repetitions, ease_factor
+	SELECT key, ` + keyStatColumns + `
 	FROM key_stats
 	WHERE (errors + successes) >= 5
 	ORDER BY CAST(errors AS REAL) / (errors + successes) DESC
@@ -373,24 +366,15 @@ func (db *DB) GetWeakestKeys(limit int) ([]KeyStat, error) {
 	}
 	defer rows.Close()
 
-	var stats []KeyStat
-	for rows.Next() {
-		var s KeyStat
-		if err := rows.Scan(&s.Key, &s.Errors, &s.Successes, &s.LastPracticed, &s.Interval, &s.Repetitions, &s.EaseFactor); err != nil {
-			return nil, err
-		}
-		stats = append(stats, s)
-	}
-
-	return stats, nil
+	return scanKeyStats(rows)
 }
 
 func (db *DB) GetDueKeys(limit int) ([]KeyStat, error) {
 	query := `
-	SELECT key, errors, successes, last_practiced, interval, repetitions, ease_factor
+	SELECT key, ` + keyStatColumns + `
 	FROM key_stats
 	WHERE (errors + successes) >= 3
-	ORDER BY last_practiced ASC
+	ORDER BY COALESCE(last_reviewed, last_practiced) ASC
 	`
 	rows, err := db.conn.Query(query)
 	if err != nil {
@@ -398,15 +382,15 @@ func (db *DB) GetDueKeys(limit int) ([]KeyStat, error) {
 	}
 	defer rows.Close()
 
+	all, err := scanKeyStats(rows)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	var stats []KeyStat
-	for rows.Next() {
-		var s KeyStat
-		if err := rows.Scan(&s.Key, &s.Errors, &s.Successes, &s.LastPracticed, &s.Interval, &s.Repetitions, &s.EaseFactor); err != nil {
-			return nil, err
-		}
-
-		daysSince := now.Sub(s.LastPracticed).Hours() / 24
+	for _, s := range all {
+		daysSince := now.Sub(s.LastReviewed).Hours() / 24
 		if daysSince >= float64(s.Interval) {
 			stats = append(stats, s)
 			if len(stats) >= limit {
@@ -420,7 +404,7 @@ func (db *DB) GetDueKeys(limit int) ([]KeyStat, error) {
 
 func (db *DB) GetAllKeyStats() ([]KeyStat, error) {
 	query := `
-	SELECT key, errors, successes, last_practiced, interval, repetitions, ease_factor
+	SELECT key, ` + keyStatColumns + `
 	FROM key_stats
 	ORDER BY (errors + successes) DESC
 	`
@@ -430,39 +414,46 @@ func (db *DB) GetAllKeyStats() ([]KeyStat, error) {
 	}
 	defer rows.Close()
 
-	var stats []KeyStat
-	for rows.Next() {
-		var s KeyStat
-		if err := rows.Scan(&s.Key, &s.Errors, &s.Successes, &s.LastPracticed, &s.Interval, &s.Repetitions, &s.EaseFactor); err != nil {
-			return nil, err
-		}
-		stats = append(stats, s)
-	}
-
-	return stats, nil
+	return scanKeyStats(rows)
 }
 
 func (db *DB) GetSessionsForGraph(limit int) ([]Session, error) {
-	query := `
-	SELECT id, text, wpm, accuracy, duration, error_count, timestamp
-	FROM sessions
-	ORDER BY timestamp ASC
-	LIMIT ?
-	`
-	rows, err := db.conn.Query(query, limit)
+	return db.QuerySessions(SessionFilter{Ascending: true, Limit: limit})
+}
+
+// keyStatColumns are the columns shared by key_stats and bigram_stats,
+// after the key column itself.
+const keyStatColumns = "errors, successes, last_practiced, last_reviewed, interval, repetitions, ease_factor, stability, difficulty"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanKeyStat reads a row selected with keyStatColumns. Rows that were
+// never scheduled since last_reviewed was added fall back to
+// last_practiced.
+func scanKeyStat(row rowScanner, k *KeyStat) error {
+	var lastReviewed sql.NullTime
+	err := row.Scan(&k.Key, &k.Errors, &k.Successes, &k.LastPracticed, &lastReviewed,
+		&k.Interval, &k.Repetitions, &k.EaseFactor, &k.Stability, &k.Difficulty)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	k.LastReviewed = k.LastPracticed
+	if lastReviewed.Valid {
+		k.LastReviewed = lastReviewed.Time
+	}
+	return nil
+}
 
-	var sessions []Session
+func scanKeyStats(rows *sql.Rows) ([]KeyStat, error) {
+	var stats []KeyStat
 	for rows.Next() {
-		var s Session
-		if err := rows.Scan(&s.ID, &s.Text, &s.WPM, &s.Accuracy, &s.Duration, &s.ErrorCount, &s.Timestamp); err != nil {
+		var k KeyStat
+		if err := scanKeyStat(rows, &k); err != nil {
 			return nil, err
 		}
-		sessions = append(sessions, s)
+		stats = append(stats, k)
 	}
-
-	return sessions, nil
+	return stats, rows.Err()
 }
@@ -305,6 +305,37 @@ func TestUpdateKeyStatsWithSRS(t *testing.T) {
 	}
 }
 
+func TestUpdateKeyStatsAligned(t *testing.T) {
+	tmpDB := "/tmp/kata_test_update_aligned.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	// One skipped "b" must not count every later key as an error.
+	if err := db.UpdateKeyStats("abcdefgh", "acdefgh"); err != nil {
+		t.Fatalf("UpdateKeyStats failed: %v", err)
+	}
+
+	allStats, err := db.GetAllKeyStats()
+	if err != nil {
+		t.Fatalf("GetAllKeyStats failed: %v", err)
+	}
+	for _, stat := range allStats {
+		wantErrors := 0
+		if stat.Key == "b" {
+			wantErrors = 1
+		}
+		if stat.Errors != wantErrors || stat.Errors+stat.Successes != 1 {
+			t.Errorf("Key %s: expected %d errors in 1 attempt, got %+v", stat.Key, wantErrors, stat)
+		}
+	}
+}
+
 func TestSaveSession(t *testing.T) {
 	tmpDB := "/tmp/kata_test_save_session.db"
 	os.Remove(tmpDB)
@@ -627,6 +658,20 @@ func TestAnalyzeErrors(t *testing.T) {
 			expectedErrors:  map[string]int{},
 			expectedBigrams: map[string]int{},
 		},
+		{
+			name:            "skipped character",
+			target:          "hello world",
+			input:           "helo world",
+			expectedErrors:  map[string]int{"l": 1},
+			expectedBigrams: map[string]int{"el": 1},
+		},
+		{
+			name:            "transposition",
+			target:          "the cat",
+			input:           "teh cat",
+			expectedErrors:  map[string]int{"h": 1, "e": 1},
+			expectedBigrams: map[string]int{"th": 1, "he": 1},
+		},
 	}
 
 	for _, tc := range cases {
@@ -0,0 +1,25 @@
+-- Schema written by kata before SRS columns and schema_version existed.
+CREATE TABLE sessions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	text TEXT NOT NULL,
+	wpm REAL NOT NULL,
+	accuracy REAL NOT NULL,
+	duration REAL NOT NULL,
+	error_count INTEGER NOT NULL,
+	timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE key_stats (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	key TEXT NOT NULL,
+	errors INTEGER DEFAULT 0,
+	successes INTEGER DEFAULT 0,
+	last_practiced DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(key)
+);
+
+INSERT INTO sessions (text, wpm, accuracy, duration, error_count, timestamp)
+VALUES ('func main', 42.0, 96.5, 12.0, 1, '2025-01-10 09:30:00');
+
+INSERT INTO key_stats (key, errors, successes, last_practiced)
+VALUES ('f', 2, 18, '2025-01-10 09:30:00');
@@ -0,0 +1,80 @@
+package stats
+
+import "time"
+
+// GetUnlockedKeys returns the keys unlocked for a keyboard layout and
+// language, in the order they were unlocked.
+func (db *DB) GetUnlockedKeys(layout, language string) ([]string, error) {
+	rows, err := db.conn.Query(`
+	SELECT key FROM unlocked_keys
+	WHERE layout = ? AND language = ?
+	ORDER BY rowid ASC
+	`, layout, language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// UnlockKeys adds keys to the unlocked set of a layout and language.
+// Keys that are already unlocked are left alone.
+func (db *DB) UnlockKeys(layout, language string, keys []string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+	INSERT OR IGNORE INTO unlocked_keys (layout, language, key, unlocked_at)
+	VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, key := range keys {
+		if _, err := stmt.Exec(layout, language, key, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetKeyLatencies returns the latency stats of every key, by key.
+func (db *DB) GetKeyLatencies() (map[string]LatencyStat, error) {
+	rows, err := db.conn.Query(`
+	SELECT key, samples, mean_ms, p50_ms, p90_ms
+	FROM latency_stats
+	WHERE kind = 'key'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	latencies := make(map[string]LatencyStat)
+	for rows.Next() {
+		var s LatencyStat
+		if err := rows.Scan(&s.Key, &s.Samples, &s.MeanMs, &s.P50Ms, &s.P90Ms); err != nil {
+			return nil, err
+		}
+		latencies[s.Key] = s
+	}
+
+	return latencies, rows.Err()
+}
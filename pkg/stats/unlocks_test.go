@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestUnlockedKeys(t *testing.T) {
+	tmpDB := "/tmp/kata_test_unlocks.db"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+
+	db, err := NewDB(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.UnlockKeys("qwerty", "english", []string{"f", "j", "d"}); err != nil {
+		t.Fatalf("UnlockKeys failed: %v", err)
+	}
+	if err := db.UnlockKeys("qwerty", "english", []string{"j", "e"}); err != nil {
+		t.Fatalf("UnlockKeys failed: %v", err)
+	}
+	if err := db.UnlockKeys("dvorak", "english", []string{"a"}); err != nil {
+		t.Fatalf("UnlockKeys failed: %v", err)
+	}
+
+	keys, err := db.GetUnlockedKeys("qwerty", "english")
+	if err != nil {
+		t.Fatalf("GetUnlockedKeys failed: %v", err)
+	}
+	if want := []string{"f", "j", "d", "e"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Expected %v, got %v", want, keys)
+	}
+
+	keys, err = db.GetUnlockedKeys("qwerty", "spanish")
+	if err != nil || len(keys) != 0 {
+		t.Errorf("Expected no keys for another language, got %v (err %v)", keys, err)
+	}
+}